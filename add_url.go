@@ -1,24 +1,31 @@
 package main
 
 import (
+	"bufio"
+	gocontext "context"
 	"fmt"
 	"net/http"
 	"os"
-	"os/user"
-	"path/filepath"
+	"strings"
 )
 
 func addUrl(feedUrl string) error {
 
-	_, err := http.Head(feedUrl)
+	req, err := newRequest(gocontext.Background(), "HEAD", feedUrl)
+	if err == nil {
+		var res *http.Response
+		res, err = http.DefaultClient.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "podcasts: %v\n\n", err)
 		return err
 	}
 
 	// get the podcast list file
-	usr, _ := user.Current()
-	feedPath := filepath.Join(usr.HomeDir, ".podcasts", "feeds.txt")
+	feedPath := config.FeedsFile
 
 	// if the file doesn't exist, create it with the feedURL string.
 	if _, err := os.Stat(feedPath); os.IsNotExist(err) {
@@ -38,3 +45,38 @@ func addUrl(feedUrl string) error {
 
 	return nil
 }
+
+// removeUrl removes the feed url from the feed list file, together with the
+// "# title" comment line before it.
+func removeUrl(feedUrl string, feedPath string) error {
+
+	file, err := os.Open(feedPath)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	found := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if fields := strings.Fields(line); len(fields) == 0 || fields[0] != feedUrl {
+			lines = append(lines, line)
+			continue
+		}
+		found = true
+		if n := len(lines); n > 0 && strings.HasPrefix(strings.TrimSpace(lines[n-1]), "#") {
+			lines = lines[:n-1]
+		}
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("%s is not in the list of podcasts", feedUrl)
+	}
+
+	return writeLines(lines, feedPath)
+}
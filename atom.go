@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const atomXmlns = "http://www.w3.org/2005/Atom"
+
+type AtomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Updated  string      `xml:"updated"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Title     string       `xml:"title"`
+	Id        string       `xml:"id"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Authors   []AtomAuthor `xml:"author"`
+	Summary   string       `xml:"summary"`
+	Content   string       `xml:"content"`
+	Links     []AtomLink   `xml:"link"`
+}
+
+type AtomAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// alternateLink returns the href of the first link with rel="alternate",
+// which is also the default when the rel attribute is missing.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// Channel maps the Atom feed onto the same model used for RSS feeds.
+func (f AtomFeed) Channel() Channel {
+
+	channel := Channel{
+		Title:         strings.TrimSpace(f.Title),
+		Link:          alternateLink(f.Links),
+		Description:   f.Subtitle,
+		PubDate:       f.Updated,
+		LastBuildDate: f.Updated,
+	}
+
+	for _, entry := range f.Entries {
+		channel.Items = append(channel.Items, entry.Item())
+	}
+
+	return channel
+}
+
+// Item maps an Atom entry onto an RSS item. Links with rel="enclosure"
+// become the enclosures of the item.
+func (e AtomEntry) Item() Item {
+
+	item := Item{
+		Title:       e.Title,
+		Link:        alternateLink(e.Links),
+		Guid:        Guid{Value: e.Id, IsPermaLink: "false"},
+		PubDate:     e.Published,
+		Description: e.Summary,
+	}
+
+	if len(item.PubDate) == 0 {
+		item.PubDate = e.Updated
+	}
+
+	if len(item.Description) == 0 {
+		item.Description = e.Content
+	}
+
+	if len(e.Authors) > 0 {
+		item.Author = e.Authors[0].Name
+	}
+
+	for _, link := range e.Links {
+		if link.Rel != "enclosure" {
+			continue
+		}
+		item.Enclosures = append(item.Enclosures, Enclosure{
+			Url:    link.Href,
+			Length: link.Length,
+			Type:   link.Type,
+		})
+	}
+
+	return item
+}
+
+// rootElement returns the name of the first element of an XML document.
+func rootElement(body []byte) (xml.Name, error) {
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return xml.Name{}, fmt.Errorf("no root element found")
+		}
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// ParseFeed detects the format of the feed body and decodes it into a
+// Channel. The content type is the Content-Type header of the response,
+// it may be empty.
+func ParseFeed(body []byte, contentType string) (Channel, error) {
+
+	if isJSONFeed(body, contentType) {
+		return parseJSONFeed(body)
+	}
+
+	root, err := rootElement(body)
+	if err != nil {
+		return Channel{}, err
+	}
+
+	switch {
+	case root.Local == "rss":
+		var feed Rss2
+		err = xml.Unmarshal(body, &feed)
+		if err != nil {
+			return Channel{}, err
+		}
+		return feed.Channel, nil
+	// only Atom feeds, not any document with a <feed> root
+	case root.Local == "feed" && root.Space == atomXmlns:
+		var feed AtomFeed
+		err = xml.Unmarshal(body, &feed)
+		if err != nil {
+			return Channel{}, err
+		}
+		return feed.Channel(), nil
+	}
+
+	if len(root.Space) > 0 {
+		return Channel{}, fmt.Errorf("unsupported feed format: <%s xmlns=%q>", root.Local, root.Space)
+	}
+	return Channel{}, fmt.Errorf("unsupported feed format: <%s>", root.Local)
+}
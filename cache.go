@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CachedFeed is the parsed channel of a feed with the validators of the
+// response it came from.
+type CachedFeed struct {
+	Url          string  `json:"url"`
+	ETag         string  `json:"etag,omitempty"`
+	LastModified string  `json:"last_modified,omitempty"`
+	Channel      Channel `json:"channel"`
+}
+
+// FeedCache stores one CachedFeed per feed url in a folder, so unchanged
+// feeds can be fetched with a conditional GET.
+type FeedCache struct {
+	dir string
+}
+
+func NewFeedCache(dir string) *FeedCache {
+	return &FeedCache{dir: dir}
+}
+
+func (c *FeedCache) path(feed_url string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%x.json", sha1.Sum([]byte(feed_url))))
+}
+
+// Load returns the cached feed, or nil if the feed isn't cached.
+func (c *FeedCache) Load(feed_url string) *CachedFeed {
+
+	b, err := ioutil.ReadFile(c.path(feed_url))
+	if err != nil {
+		return nil
+	}
+
+	var cached CachedFeed
+	if err := json.Unmarshal(b, &cached); err != nil || cached.Url != feed_url {
+		return nil
+	}
+	return &cached
+}
+
+// Store writes the cached feed to a temporary file and renames it, so a
+// concurrent Load never reads a partial file.
+func (c *FeedCache) Store(cached CachedFeed) error {
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(cached.Url)
+	tmp, err := ioutil.TempFile(c.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,838 @@
+package main
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"syscall"
+	"text/template"
+	"time"
+)
+
+// Command is a subcommand of podcasts, such as "add" or "update".
+type Command struct {
+	Name      string
+	UsageLine string
+	Short     string
+	// Flags is filled by the command's setup function.
+	Flags *flag.FlagSet
+	// Run returns the exit code of the command.
+	Run func(cmd *Command, args []string) int
+}
+
+func (cmd *Command) Usage() {
+	fmt.Fprintf(os.Stderr, "usage: podcasts %s\n\n%s.\n", cmd.UsageLine, cmd.Short)
+	if hasFlags(cmd.Flags) {
+		fmt.Fprintf(os.Stderr, "\nflags:\n")
+		cmd.Flags.PrintDefaults()
+	}
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+// commands is the list of commands, in the order of the help output.
+var commands = []*Command{addCmd, removeCmd, listCmd, updateCmd, downloadCmd, showCmd, episodesCmd, serveCmd}
+
+// newCommand creates a command. setup declares the flags of the command on
+// its FlagSet.
+func newCommand(name string, usageLine string, short string, setup func(fs *flag.FlagSet), run func(cmd *Command, args []string) int) *Command {
+
+	cmd := &Command{
+		Name:      name,
+		UsageLine: usageLine,
+		Short:     short,
+		Flags:     flag.NewFlagSet(name, flag.ContinueOnError),
+		Run:       run,
+	}
+	cmd.Flags.Usage = cmd.Usage
+	if setup != nil {
+		setup(cmd.Flags)
+	}
+	return cmd
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "podcasts is a command line podcast client.\n\n")
+	fmt.Fprintf(os.Stderr, "usage: podcasts <command> [flags] [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.Name, cmd.Short)
+	}
+	fmt.Fprintf(os.Stderr, "\nWithout a command, podcasts runs \"update -script\".\n")
+	fmt.Fprintf(os.Stderr, "Use \"podcasts help <command>\" for the flags of a command.\n")
+}
+
+// runCommand runs the named command and returns the exit code.
+func runCommand(name string, args []string) int {
+
+	for _, cmd := range commands {
+		setFlagDefaults(cmd.Flags, config.Flags)
+	}
+
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		if len(args) > 0 {
+			if cmd := lookupCommand(args[0]); cmd != nil {
+				cmd.Usage()
+				return 0
+			}
+		}
+		usage()
+		return 0
+	}
+
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "podcasts: unknown command %q\n\n", name)
+		usage()
+		return 2
+	}
+
+	if err := cmd.Flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	return cmd.Run(cmd, cmd.Flags.Args())
+}
+
+// setFlagDefaults sets the flags of fs that are in defaults, the values of
+// the config file, before the command line is parsed.
+func setFlagDefaults(fs *flag.FlagSet, defaults map[string]string) {
+	for name, value := range defaults {
+		if f := fs.Lookup(name); f != nil && f.Value.Set(value) == nil {
+			f.DefValue = value
+		}
+	}
+}
+
+func lookupCommand(name string) *Command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// resolveFeed returns the feed url for an argument that is either a url or
+// the number of the feed as printed by "podcasts list".
+func resolveFeed(arg string, feed_list []string) (string, error) {
+
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(feed_list) {
+			return "", fmt.Errorf("no feed number %d, there are %d feeds", n, len(feed_list))
+		}
+		return feed_list[n-1], nil
+	}
+
+	match, _ := regexp.MatchString(HTTPS_REGEX, arg)
+	if match == false {
+		return "", fmt.Errorf("not a feed url or number: %q", arg)
+	}
+	return arg, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// openState opens the episode state database in the data folder.
+func openState() (*StateDB, error) {
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		return nil, err
+	}
+	return OpenStateDB(filepath.Join(config.DataDir, "state.json"))
+}
+
+func saveState(state *StateDB) int {
+	if err := state.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+var addOpml *string
+
+var addCmd = newCommand("add", "add [-opml file] [url ...]",
+	"Add feed urls to the list of podcasts",
+	func(fs *flag.FlagSet) {
+		addOpml = fs.String("opml", ``, "Import the feeds of an OPML file")
+	},
+	runAdd)
+
+func runAdd(cmd *Command, args []string) int {
+
+	opml := *addOpml
+	if len(args) == 0 && len(opml) == 0 {
+		cmd.Usage()
+		return 2
+	}
+
+	feed_list, feed_path, _ := GetFeedList()
+
+	status := 0
+	if len(opml) > 0 {
+		added, err := ImportOpml(opml, feed_path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			status = 1
+		} else {
+			fmt.Printf("%d feeds imported from %s\n", added, opml)
+		}
+	}
+
+	for _, feedUrl := range args {
+		match, _ := regexp.MatchString(HTTPS_REGEX, feedUrl)
+		if match == false {
+			fmt.Fprintf(os.Stderr, "podcasts: not a feed url: %q\n", feedUrl)
+			status = 1
+			continue
+		}
+		if contains(feed_list, feedUrl) {
+			fmt.Fprintf(os.Stderr, "podcasts: %s is already in the list of podcasts\n", feedUrl)
+			continue
+		}
+		if err := addUrl(feedUrl); err != nil {
+			status = 1
+			continue
+		}
+		feed_list = append(feed_list, feedUrl)
+	}
+
+	return status
+}
+
+var removeCmd = newCommand("remove", "remove <url|number> ...",
+	"Remove feeds from the list of podcasts",
+	nil,
+	runRemove)
+
+func runRemove(cmd *Command, args []string) int {
+
+	if len(args) == 0 {
+		cmd.Usage()
+		return 2
+	}
+
+	feed_list, feed_path, err := GetFeedList()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	// resolve every argument first, the numbers change after a removal
+	var urls []string
+	for _, arg := range args {
+		feedUrl, err := resolveFeed(arg, feed_list)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return 1
+		}
+		urls = append(urls, feedUrl)
+	}
+
+	status := 0
+	for _, feedUrl := range urls {
+		if err := removeUrl(feedUrl, feed_path); err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+var (
+	listOpml        *bool
+	listConcurrency *int
+	listTimeout     *time.Duration
+)
+
+var listCmd = newCommand("list", "list [-opml]",
+	"List the podcasts",
+	func(fs *flag.FlagSet) {
+		listOpml = fs.Bool("opml", false, "Print the list as OPML, with the titles fetched from the feeds")
+		listConcurrency = fs.Int("concurrency", 8, "Number of feeds fetched at the same time for -opml")
+		listTimeout = fs.Duration("timeout", 30*time.Second, "Timeout of each feed request for -opml, 0 for none")
+	},
+	runList)
+
+func runList(cmd *Command, args []string) int {
+
+	feed_list, feed_path, err := GetFeedList()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	if *listOpml {
+		ctx, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := FetchOptions{
+			Cache:       NewFeedCache(filepath.Join(config.CacheDir, "feeds")),
+			Concurrency: *listConcurrency,
+			Timeout:     *listTimeout,
+		}
+		if err := ExportOpml(ctx, feed_list, feed_path, os.Stdout, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	titles, _ := readFeedTitles(feed_path)
+	for i, feedUrl := range feed_list {
+		fmt.Printf("%3d : %-30s : %s\n", i+1, titles[feedUrl], feedUrl)
+	}
+	return 0
+}
+
+// updateOptions are the flags of the update and download commands.
+type updateOptions struct {
+	Days        int
+	DaysSet     bool
+	Since       string
+	Until       string
+	Latest      int
+	All         bool
+	Script      bool
+	Format      string
+	Template    string
+	FeedTitle   string
+	BaseUrl     string
+	Output      string
+	Dir         string
+	Workers     int
+	Concurrency int
+	Timeout     time.Duration
+	Deadline    time.Duration
+	Summary     string
+}
+
+// fetchFlags declares the flags shared by update and download.
+func fetchFlags(fs *flag.FlagSet, o *updateOptions) {
+	fs.IntVar(&o.Days, "days", 1, "Number of days back to download an episode, unless -since is set")
+	fs.StringVar(&o.Since, "since", ``, "Only the episodes published after a date (2026-10-01), a duration ago (36h, 3d) or the last run (last-run)")
+	fs.StringVar(&o.Until, "until", ``, "Only the episodes published up to a date (included) or before a duration ago")
+	fs.IntVar(&o.Latest, "latest", 0, "Only the newest n episodes of each feed, without -days or -since from any date")
+	fs.BoolVar(&o.All, "all", false, "Include the episodes seen in previous runs")
+	fs.IntVar(&o.Workers, "workers", 4, "Number of concurrent downloads")
+	fs.IntVar(&o.Concurrency, "concurrency", 8, "Number of feeds fetched at the same time")
+	fs.DurationVar(&o.Timeout, "timeout", 30*time.Second, "Timeout of each feed request, 0 for none")
+	fs.DurationVar(&o.Deadline, "deadline", 0, "Stop fetching and downloading after this long, 0 for never")
+	fs.StringVar(&o.Summary, "summary", "table", "Format of the per feed summary: table, json or none")
+}
+
+var updateOpts updateOptions
+
+var updateCmd = newCommand("update", "update [-script] [-format format] [-template name|file] [-days n] [-output file] [-download dir]",
+	"Fetch the feeds and record the new episodes, optionally as a wget script",
+	func(fs *flag.FlagSet) {
+		fetchFlags(fs, &updateOpts)
+		fs.BoolVar(&updateOpts.Script, "script", false, "Print a wget script of the new episodes")
+		fs.StringVar(&updateOpts.Format, "format", "script", "Format of the new episodes: script (as -script), json, ndjson, m3u8, pls, rss, html, markdown or text")
+		fs.StringVar(&updateOpts.Template, "template", ``, "Render the new episodes with a built-in template (script, markdown or text) or a text/template file")
+		fs.StringVar(&updateOpts.FeedTitle, "title", "Podcasts", "Title of the merged rss feed or the digest")
+		fs.StringVar(&updateOpts.BaseUrl, "base-url", ``, "Url the download folder is served at, for the merged rss feed")
+		fs.StringVar(&updateOpts.Output, "output", ``, "Path of the output file")
+		fs.StringVar(&updateOpts.Dir, "download", ``, "Download the episodes into this folder")
+	},
+	runUpdate)
+
+func runUpdate(cmd *Command, args []string) int {
+	updateOpts.DaysSet = isFlagSet(cmd.Flags, "days")
+	// -script on the command line wins over the format of the config file
+	if updateOpts.Script && !isFlagSet(cmd.Flags, "format") {
+		updateOpts.Format = "script"
+	}
+	// -format script, on the command line or in the config file, is -script
+	_, configFormat := config.Flags["format"]
+	if updateOpts.Format == "script" && (isFlagSet(cmd.Flags, "format") || configFormat) {
+		updateOpts.Script = true
+	}
+	return update(updateOpts)
+}
+
+var downloadOpts updateOptions
+
+var downloadCmd = newCommand("download", "download [-dir dir] [-days n] [-workers n]",
+	"Fetch the feeds and download the new episodes",
+	func(fs *flag.FlagSet) {
+		fetchFlags(fs, &downloadOpts)
+		fs.StringVar(&downloadOpts.Dir, "dir", `.`, "Download the episodes into this folder")
+	},
+	runDownload)
+
+func runDownload(cmd *Command, args []string) int {
+	downloadOpts.DaysSet = isFlagSet(cmd.Flags, "days")
+	return update(downloadOpts)
+}
+
+// isFlagSet reports whether the flag was given on the command line.
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// window returns the episode window of the -days, -since, -until and
+// -latest flags.
+func (o updateOptions) window(now time.Time) (Window, error) {
+
+	w := Window{Latest: o.Latest}
+	days := now.Add(-time.Duration(o.Days) * 24 * time.Hour)
+
+	var err error
+	switch {
+	case o.Since == lastRun:
+		// for the feeds never fetched
+		w.SinceLastRun, w.Since = true, days
+	case len(o.Since) > 0:
+		w.Since, err = ParseTimeBound(o.Since, now, false)
+		if err != nil {
+			return w, err
+		}
+	case o.Latest > 0 && !o.DaysSet:
+	default:
+		w.Since = days
+	}
+
+	w.Until, err = ParseTimeBound(o.Until, now, true)
+	return w, err
+}
+
+// update fetches every feed, then writes the wget script of the new
+// episodes if o.Script is set, or their JSON, playlist, merged feed or
+// digest if o.Format or o.Template asks for it, and downloads them if o.Dir is not empty.
+// The first Ctrl-C stops the fetches and downloads in flight but still
+// writes the results of the feeds that finished, a second one exits.
+func update(o updateOptions) int {
+
+	feed_list, feed_path, _ := GetFeedList()
+	feed_data_folder := config.CacheDir
+
+	configs, err := ReadFeedConfigs(feed_path, config.Feeds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 2
+	}
+	enabled := feed_list[:0:0]
+	for _, feed_url := range feed_list {
+		if !configs[feed_url].Disabled {
+			enabled = append(enabled, feed_url)
+		}
+	}
+	feed_list = enabled
+
+	state, err := openState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	signals, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-signals.Done()
+		// restore the default behavior for the next signal
+		stop()
+	}()
+
+	ctx := signals
+	if o.Deadline > 0 {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(signals, o.Deadline)
+		defer cancel()
+	}
+
+	window, err := o.window(time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 2
+	}
+
+	opts := FetchOptions{
+		Window:      window,
+		All:         o.All,
+		State:       state,
+		Cache:       NewFeedCache(filepath.Join(config.CacheDir, "feeds")),
+		Concurrency: o.Concurrency,
+		Timeout:     o.Timeout,
+		Feeds:       configs,
+	}
+	if len(o.Dir) > 0 {
+		opts.Queue = &DownloadQueue{}
+	}
+
+	// the episodes in any format but the script are written after the
+	// downloads, so they have their final state and local files
+	episodeOutput := false
+	switch o.Format {
+	case "", "script":
+	case "json", "ndjson", "m3u8", "pls", "rss", "html", "markdown", "text":
+		episodeOutput = true
+	default:
+		fmt.Fprintf(os.Stderr, "podcasts: unknown episode format %q\n", o.Format)
+		return 2
+	}
+
+	// markdown and text are built-in templates
+	var tmpl *template.Template
+	if len(o.Template) == 0 && (o.Format == "markdown" || o.Format == "text") {
+		o.Template = o.Format
+	}
+	if len(o.Template) > 0 {
+		tmpl, err = loadDigestTemplate(o.Template)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return 2
+		}
+		episodeOutput = true
+	}
+
+	// keep stdout for the episodes when they are piped
+	var summary io.Writer = os.Stdout
+	if episodeOutput && len(o.Output) == 0 {
+		summary = os.Stderr
+	}
+
+	var report func(FetchResult)
+	switch o.Summary {
+	case "table":
+		printTableHeader(summary)
+		report = func(r FetchResult) { fmt.Fprintln(summary, r) }
+	case "json", "none":
+	default:
+		fmt.Fprintf(os.Stderr, "podcasts: unknown summary format %q\n", o.Summary)
+		return 2
+	}
+
+	start := time.Now()
+	results, err := fetchFeeds(ctx, feed_list, feed_data_folder, opts, report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		saveState(state)
+		return 1
+	}
+
+	switch o.Summary {
+	case "table":
+		printTableFooter(summary, results, time.Since(start))
+	case "json":
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(summary, "%s\n", b)
+	}
+
+	if o.Script && !episodeOutput {
+		feed_text := mergeDataOfFiles(feed_data_folder, ".feed")
+
+		if len(o.Output) == 0 {
+			fmt.Print(feed_text)
+		} else {
+			writeText(feed_text, o.Output)
+		}
+	}
+
+	failed := 0
+	if opts.Queue != nil {
+		downloads := opts.Queue.Downloads()
+		start = time.Now()
+		failed = DownloadAll(ctx, downloads, o.Dir, o.Workers, os.Stderr, state)
+		fmt.Fprintf(os.Stderr, "\n%d of %d downloads failed, %5.2fs elapsed\n", failed, len(downloads), time.Since(start).Seconds())
+
+		// keep the newest max episodes of the feeds with a max setting,
+		// unless the run was stopped
+		if ctx.Err() == nil {
+			failed += PruneDownloads(o.Dir, configs, state, os.Stderr)
+		}
+	}
+
+	if episodeOutput {
+		var buf bytes.Buffer
+		switch {
+		case tmpl != nil:
+			err = writeTemplateDigest(&buf, tmpl, results, o.FeedTitle)
+		case o.Format == "json":
+			err = writeEpisodesJSON(&buf, results, state)
+		case o.Format == "ndjson":
+			err = writeEpisodesNDJSON(&buf, results, state)
+		case o.Format == "m3u8":
+			err = writeM3U8(&buf, playlistEntries(results, o.Dir))
+		case o.Format == "pls":
+			err = writePLS(&buf, playlistEntries(results, o.Dir))
+		case o.Format == "rss":
+			err = writeMergedFeed(&buf, results, FeedOptions{Title: o.FeedTitle, Link: o.BaseUrl, Dir: o.Dir, BaseUrl: o.BaseUrl})
+		case o.Format == "html":
+			err = writeDigest(&buf, results, FeedOptions{Title: o.FeedTitle, Dir: o.Dir, BaseUrl: o.BaseUrl})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return 1
+		}
+
+		if len(o.Output) == 0 {
+			os.Stdout.Write(buf.Bytes())
+		} else {
+			writeText(buf.String(), o.Output)
+		}
+	}
+
+	status := saveState(state)
+
+	// delete the .feed files if they exist
+	//feedExtensions := []string{".feed"}
+	// clean up before finishing...
+	err_walker := filepath.Walk(feed_data_folder, deleteFiles)
+	if err_walker != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err_walker)
+		status = 1
+	}
+
+	if status == 0 {
+		status = exitStatus(results, failed)
+	}
+	return status
+}
+
+var showItems *int
+
+var showCmd = newCommand("show", "show [-n count] <url|number>",
+	"Show a podcast and its latest episodes",
+	func(fs *flag.FlagSet) {
+		showItems = fs.Int("n", 10, "Number of episodes to show, 0 for all")
+	},
+	runShow)
+
+func runShow(cmd *Command, args []string) int {
+
+	if len(args) != 1 {
+		cmd.Usage()
+		return 2
+	}
+
+	feed_list, _, _ := GetFeedList()
+	feedUrl, err := resolveFeed(args[0], feed_list)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	channel, err := GetPodcastData(feedUrl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(channel)
+	for i, d := range SortItems(channel) {
+		if *showItems > 0 && i >= *showItems {
+			break
+		}
+		item := d.Item
+		fmt.Printf("#\n%s", item)
+		for _, encl := range item.Enclosures {
+			fmt.Printf("# Enclosure: %s\n", encl)
+		}
+	}
+	return 0
+}
+
+var (
+	episodesFeed    *string
+	episodesStatus  *string
+	episodesPlayed  *string
+	episodesSkipped *string
+)
+
+var episodesCmd = newCommand("episodes", "episodes [-feed url|number] [-status status] [-played guid] [-skipped guid]",
+	"List the episodes of previous runs, or mark them as played or skipped",
+	func(fs *flag.FlagSet) {
+		episodesFeed = fs.String("feed", ``, "Only the episodes of this feed")
+		episodesStatus = fs.String("status", ``, "Only the episodes with this status: seen, downloaded, played or skipped")
+		episodesPlayed = fs.String("played", ``, "Mark the episode with this guid as played")
+		episodesSkipped = fs.String("skipped", ``, "Mark the episode with this guid as skipped")
+	},
+	runEpisodes)
+
+func runEpisodes(cmd *Command, args []string) int {
+
+	feed_list, _, _ := GetFeedList()
+	state, err := openState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	// mark episodes and exit
+	if len(*episodesPlayed) > 0 || len(*episodesSkipped) > 0 {
+		status := 0
+		status |= markEpisode(state, *episodesPlayed, StatusPlayed)
+		status |= markEpisode(state, *episodesSkipped, StatusSkipped)
+		return status | saveState(state)
+	}
+
+	feedUrl := ""
+	if len(*episodesFeed) > 0 {
+		feedUrl, err = resolveFeed(*episodesFeed, feed_list)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return 1
+		}
+	}
+
+	type episode struct {
+		feed  string
+		key   string
+		state EpisodeState
+	}
+
+	var episodes []episode
+	for feed, states := range state.Feeds {
+		if len(feedUrl) > 0 && feed != feedUrl {
+			continue
+		}
+		for key, s := range states {
+			if len(*episodesStatus) > 0 && s.Status() != *episodesStatus {
+				continue
+			}
+			episodes = append(episodes, episode{feed, key, *s})
+		}
+	}
+
+	// newest first
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].state.FirstSeen.After(episodes[j].state.FirstSeen)
+	})
+
+	for _, e := range episodes {
+		title := e.state.Title
+		if len(title) > 40 {
+			title = title[:40]
+		}
+		fmt.Printf("%-10s : %s : %-40s : %s\n", e.state.Status(), e.state.FirstSeen.Local().Format("2006-01-02 15:04"), title, e.key)
+	}
+	return 0
+}
+
+// markEpisode sets the status of the episodes with the given guid.
+func markEpisode(state *StateDB, guid string, status string) int {
+
+	if len(guid) == 0 {
+		return 0
+	}
+
+	n, err := state.MarkAll(guid, status)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+	if n == 0 {
+		fmt.Fprintf(os.Stderr, "podcasts: no episode with guid %q\n", guid)
+		return 1
+	}
+	return 0
+}
+
+var (
+	serveAddr    *string
+	serveDir     *string
+	serveTitle   *string
+	serveRefresh *time.Duration
+	serveTimeout *time.Duration
+)
+
+var serveCmd = newCommand("serve", "serve [-addr host:port] [-dir dir] [-refresh interval]",
+	"Serve the downloaded episodes and their feeds over http",
+	func(fs *flag.FlagSet) {
+		serveAddr = fs.String("addr", ":8080", "Address to listen on")
+		serveDir = fs.String("dir", `.`, "Folder of the downloaded episodes")
+		serveTitle = fs.String("title", "Podcasts", "Title of the merged feed")
+		serveRefresh = fs.Duration("refresh", time.Hour, "Fetch the feeds again after this long, 0 for never")
+		serveTimeout = fs.Duration("timeout", 30*time.Second, "Timeout of each feed request, 0 for none")
+	},
+	runServe)
+
+// runServe serves the download folder until Ctrl-C, the feeds are fetched
+// when it starts and then every -refresh.
+func runServe(cmd *Command, args []string) int {
+
+	feed_list, feed_path, _ := GetFeedList()
+	configs, err := ReadFeedConfigs(feed_path, config.Feeds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+
+	mirror := &Mirror{
+		Dir:     *serveDir,
+		Title:   *serveTitle,
+		Feeds:   feed_list,
+		Configs: configs,
+		Cache:   NewFeedCache(filepath.Join(config.CacheDir, "feeds")),
+		Timeout: *serveTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mirror.Refresh(ctx)
+
+	if *serveRefresh > 0 {
+		go func() {
+			ticker := time.NewTicker(*serveRefresh)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					mirror.Refresh(ctx)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	srv := &http.Server{Addr: *serveAddr, Handler: mirror.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := gocontext.WithTimeout(gocontext.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(os.Stderr, "podcasts: serving %s on %s\n", *serveDir, *serveAddr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		return 1
+	}
+	return 0
+}
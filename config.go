@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of podcasts, read from
+// $XDG_CONFIG_HOME/podcasts/config.toml:
+//
+//	concurrency = 8
+//	workers = 4
+//	timeout = "30s"
+//	deadline = "10m"
+//	download_dir = "~/Podcasts"
+//	format = "m3u8"
+//	user_agent = "podcasts"
+//
+//	[feed."https://example.com/feed.xml"]
+//	include = "(?i)interview"
+//	max = 3
+//
+// The PODCASTS_* environment variables in configEnv override the file.
+type Config struct {
+	// Path is the config file, FeedsFile the feed list next to it.
+	Path      string
+	FeedsFile string
+	// DataDir holds the episode state, CacheDir the fetched feeds.
+	DataDir  string
+	CacheDir string
+	// Flags are the defaults of the command flags set in the config file or
+	// the environment, keyed by flag name.
+	Flags     map[string]string
+	UserAgent string
+	// Feeds are the settings of the [feed."url"] tables, the settings on
+	// the line of the feed in the feed list override them.
+	Feeds map[string]FeedConfig
+}
+
+// config is loaded by main before the command runs.
+var config Config
+
+// configFlags maps the settings of the config file to the flags they are
+// the default of.
+var configFlags = map[string]string{
+	"concurrency":  "concurrency",
+	"workers":      "workers",
+	"timeout":      "timeout",
+	"deadline":     "deadline",
+	"download_dir": "dir",
+	"format":       "format",
+}
+
+// configEnv maps the environment variables to the settings they override.
+var configEnv = map[string]string{
+	"PODCASTS_CONCURRENCY":  "concurrency",
+	"PODCASTS_WORKERS":      "workers",
+	"PODCASTS_TIMEOUT":      "timeout",
+	"PODCASTS_DEADLINE":     "deadline",
+	"PODCASTS_DOWNLOAD_DIR": "download_dir",
+	"PODCASTS_FORMAT":       "format",
+	"PODCASTS_USER_AGENT":   "user_agent",
+}
+
+const defaultConfig = `# podcasts configuration, the PODCASTS_* environment variables override it
+# and the command line flags override both.
+
+# concurrency = 8            # feeds fetched at the same time
+# workers = 4                # concurrent downloads
+# timeout = "30s"            # timeout of each feed request, "0s" for none
+# deadline = "10m"           # stop fetching and downloading after this long
+# download_dir = "~/Podcasts"
+# format = "script"          # output of update: script, json, m3u8, rss, html...
+# user_agent = "podcasts"
+
+# Settings of a feed of feeds.txt, the settings on its line override them.
+# [feed."https://example.com/feed.xml"]
+# include = "(?i)interview"
+# exclude = "trailer"
+# max = 3
+# type = "audio/mpeg"
+# dir = "Example"
+# disabled = true
+`
+
+// LoadConfig finds the folders of podcasts, moves the files of older
+// versions there, and reads the config file and the environment. The config
+// file is $PODCASTS_CONFIG if set.
+func LoadConfig() (Config, error) {
+
+	// $HOME, not needed when the XDG variables are set
+	home, _ := os.UserHomeDir()
+
+	c := Config{
+		Path:     filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), "config.toml"),
+		DataDir:  xdgDir("XDG_DATA_HOME", home, ".local", "share"),
+		CacheDir: xdgDir("XDG_CACHE_HOME", home, ".cache"),
+		Flags:    make(map[string]string),
+		Feeds:    make(map[string]FeedConfig),
+	}
+	if path := os.Getenv("PODCASTS_CONFIG"); len(path) > 0 {
+		c.Path = path
+	}
+	c.FeedsFile = filepath.Join(filepath.Dir(c.Path), "feeds.txt")
+
+	if len(home) > 0 {
+		if err := c.migrate(filepath.Join(home, ".podcasts")); err != nil {
+			return c, err
+		}
+	}
+
+	b, err := ioutil.ReadFile(c.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return c, err
+	}
+	if err == nil {
+		if err := c.parse(string(b)); err != nil {
+			return c, err
+		}
+	}
+
+	for env, key := range configEnv {
+		if value, ok := os.LookupEnv(env); ok {
+			if err := c.set(key, value); err != nil {
+				return c, fmt.Errorf("%s: %v", env, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// xdgDir is the podcasts folder in the XDG base directory of the variable
+// env, or in the default one under home. Relative paths in env are ignored
+// as the specification asks.
+func xdgDir(env string, home string, fallback ...string) string {
+
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "podcasts")
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), "podcasts")...)
+}
+
+// parse reads the settings of the config file.
+func (c *Config) parse(text string) error {
+
+	tables, err := parseToml(text)
+	if err != nil {
+		return fmt.Errorf("%s:%v", c.Path, err)
+	}
+
+	for _, table := range tables {
+
+		switch {
+		case len(table.Name) == 0:
+			for key, v := range table.Values {
+				if err := c.set(key, v.Text); err != nil {
+					return fmt.Errorf("%s:%d: %v", c.Path, v.Line, err)
+				}
+			}
+		case len(table.Name) == 2 && table.Name[0] == "feed":
+			feed := FeedConfig{Url: table.Name[1]}
+			for key, v := range table.Values {
+				if err := feed.Set(key, v.Text); err != nil {
+					return fmt.Errorf("%s:%d: %v", c.Path, v.Line, err)
+				}
+			}
+			c.Feeds[feed.Url] = feed
+		default:
+			return fmt.Errorf("%s:%d: unknown table [%s]", c.Path, table.Line, strings.Join(table.Name, "."))
+		}
+	}
+	return nil
+}
+
+// set sets a setting of the config file.
+func (c *Config) set(key string, value string) error {
+
+	switch key {
+	case "concurrency", "workers":
+		if n, err := strconv.Atoi(value); err != nil || n < 1 {
+			return fmt.Errorf("%s: %q is not a positive number", key, value)
+		}
+	case "timeout", "deadline":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	case "download_dir":
+		value = expandHome(value)
+	case "format":
+	case "user_agent":
+		c.UserAgent = value
+		return nil
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+
+	c.Flags[configFlags[key]] = value
+	return nil
+}
+
+// expandHome replaces a leading "~/" with the home directory.
+func expandHome(path string) string {
+
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// migrate moves the feed list and the episode state of the older versions,
+// in legacy, to the folders of c, once. The feed cache is fetched again.
+func (c Config) migrate(legacy string) error {
+
+	old_feeds := filepath.Join(legacy, "feeds.txt")
+	if _, err := os.Stat(c.FeedsFile); !os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(old_feeds); err != nil {
+		return nil
+	}
+
+	// the feed list last, it tells the migration is done
+	moves := [][2]string{
+		{filepath.Join(legacy, "state.json"), filepath.Join(c.DataDir, "state.json")},
+		{old_feeds, c.FeedsFile},
+	}
+	for _, m := range moves {
+		if _, err := os.Stat(m[0]); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(m[1]), 0755); err != nil {
+			return err
+		}
+		if err := moveFile(m[0], m[1]); err != nil {
+			return err
+		}
+	}
+
+	if err := writeDefaultConfig(c.Path); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "podcasts: moved the feed list from %s to %s and the episode state to %s\n", legacy, c.FeedsFile, c.DataDir)
+	return nil
+}
+
+// moveFile renames src to dst, or copies it when they are on different
+// file systems.
+func moveFile(src string, dst string) error {
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// writeDefaultConfig writes the commented out default config file if there
+// is none.
+func writeDefaultConfig(path string) error {
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(defaultConfig), 0644)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// digestStyle keeps the digest a single file.
+const digestStyle = `body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; color: #222; }
+section { border-top: 1px solid #ccc; padding-top: 1em; margin-top: 2em; }
+section > header { display: flex; align-items: center; gap: 1em; }
+section > header img { width: 96px; height: 96px; object-fit: cover; }
+article { margin: 1.5em 0; }
+article img { float: right; width: 64px; height: 64px; object-fit: cover; margin-left: 1em; }
+.meta { color: #666; font-size: 0.9em; }
+.notes { clear: both; }
+`
+
+// blockTags are the tags that end a line of show notes.
+var blockTags = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6]|/tr)\b[^>]*>`)
+
+// showNotes renders untrusted feed HTML as escaped paragraphs of text.
+func showNotes(desc string) string {
+
+	text := html.UnescapeString(StripTags(blockTags.ReplaceAllString(desc, "\n")))
+
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 0 {
+			paragraphs = append(paragraphs, "<p>"+htmlEscaper(line)+"</p>")
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// urlAttr is an untrusted url made safe for a quoted attribute, the way
+// html/template escapes {{.}} in href="{{.}}".
+func urlAttr(u string) string {
+	return attrEscaper(urlNormalizer(urlFilter(u)))
+}
+
+// writeDigest writes an HTML page with the new episodes grouped by feed.
+// The enclosures found in opts.Dir link to the local copies.
+func writeDigest(w io.Writer, results []FetchResult, opts FeedOptions) error {
+
+	title := opts.Title
+	if len(title) == 0 {
+		title = "Podcasts"
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n", htmlEscaper(title), digestStyle)
+	fmt.Fprintf(bw, "<h1>%s</h1>\n<p class=\"meta\">%s</p>\n", htmlEscaper(title), time.Now().Format("2006-01-02 15:04"))
+
+	for _, r := range results {
+		if r.Failed() || len(r.Episodes) == 0 {
+			continue
+		}
+
+		c := r.Channel
+		fmt.Fprintf(bw, "<section>\n<header>\n")
+		if len(c.ITunesImage.Href) > 0 {
+			fmt.Fprintf(bw, "<img src=\"%s\" alt=\"\">\n", urlAttr(c.ITunesImage.Href))
+		}
+		fmt.Fprintf(bw, "<h2><a href=\"%s\">%s</a></h2>\n</header>\n", urlAttr(strings.TrimSpace(c.Link)), htmlEscaper(strings.TrimSpace(c.Title)))
+
+		for _, item := range r.Episodes {
+			writeDigestItem(bw, item, r.Subdir, opts)
+		}
+		fmt.Fprintf(bw, "</section>\n")
+	}
+
+	fmt.Fprintf(bw, "</body>\n</html>\n")
+	return bw.Flush()
+}
+
+func writeDigestItem(w io.Writer, item Item, subdir string, opts FeedOptions) {
+
+	fmt.Fprintf(w, "<article>\n")
+	if len(item.ITunesImage.Href) > 0 {
+		fmt.Fprintf(w, "<img src=\"%s\" alt=\"\">\n", urlAttr(item.ITunesImage.Href))
+	}
+
+	fmt.Fprintf(w, "<h3>%s</h3>\n", htmlEscaper(strings.TrimSpace(item.Title)))
+
+	var meta []string
+	if t, err := ParseTime(item.PubDate); err == nil && !t.IsZero() {
+		meta = append(meta, t.Format("2006-01-02"))
+	}
+	if item.ITunesDuration > 0 {
+		meta = append(meta, item.ITunesDuration.String())
+	}
+	if n := item.episodeNumber(); len(n) > 0 {
+		meta = append(meta, n)
+	}
+	for _, encl := range item.Enclosures {
+		name, err := GetFileName(encl.String())
+		if err != nil {
+			name = "download"
+		}
+		// the file:// urls of the local copies are ours, urlFilter would
+		// reject them
+		href := urlAttr(encl.Url)
+		if local := localEnclosureUrl(encl, subdir, opts); local != encl.Url {
+			href = attrEscaper(urlNormalizer(local))
+		}
+		meta = append(meta, fmt.Sprintf("<a href=\"%s\">%s</a>", href, htmlEscaper(name)))
+	}
+	if len(meta) > 0 {
+		fmt.Fprintf(w, "<p class=\"meta\">%s</p>\n", strings.Join(meta, " &middot; "))
+	}
+
+	desc := item.Description
+	if len(strings.TrimSpace(desc)) == 0 {
+		desc = item.ITunesSummary
+	}
+	if notes := showNotes(desc); len(notes) > 0 {
+		fmt.Fprintf(w, "<div class=\"notes\">\n%s\n</div>\n", notes)
+	}
+	fmt.Fprintf(w, "</article>\n")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"go/doc"
+	"html"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DigestData is the data of a digest template.
+//
+//	.Title      the -title flag
+//	.Generated  the time of the run
+//	.Feeds      the feeds that were fetched, in the order of feeds.txt
+//
+// Each DigestFeed has .Url, .Channel and .Items, the new episodes. The
+// fields of a Channel are .Title, .Link, .Description, .PubDate,
+// .LastBuildDate, .ITunesAuthor, .ITunesSummary and .ITunesImage.Href;
+// an Item has .Title, .Link, .Guid (.Guid.Value as a function argument),
+// .PubDate, .Author, .Description, .ITunesSummary, .ITunesDuration,
+// .ITunesSeason, .ITunesEpisode and .Enclosures; an Enclosure has .Url,
+// .Length and .Type. {{.}} of a
+// Channel or an Item is its block in the wget script, of an Enclosure its
+// url without the query.
+type DigestData struct {
+	Title     string
+	Generated time.Time
+	Feeds     []DigestFeed
+}
+
+type DigestFeed struct {
+	Url     string
+	Channel Channel
+	Items   []Item
+}
+
+// digestFuncs are the functions available to the digest templates.
+var digestFuncs = template.FuncMap{
+	// header is the first line of the wget script
+	"header": func() string { return constructPodcastHeader(PARAGRAPH_WIDTH) },
+	// quote quotes a string for the shell
+	"quote": ShellQuote,
+	// filename is the name an enclosure is downloaded to
+	"filename": func(e Enclosure) string {
+		name, err := GetFileName(e.String())
+		if err != nil {
+			return ""
+		}
+		return name
+	},
+	// strip turns feed HTML into plain text
+	"strip": func(s string) string { return strings.TrimSpace(html.UnescapeString(StripTags(s))) },
+	// single joins the lines of a string
+	"single": singleLine,
+	// truncate cuts a string after n bytes and adds " ..."
+	"truncate": func(n int, s string) string {
+		if len(s) > n {
+			return s[:n] + " ..."
+		}
+		return s
+	},
+	// wrap fills the paragraphs of a string to width, each line starting
+	// with prefix
+	"wrap": func(prefix string, width int, s string) string {
+		var buf bytes.Buffer
+		doc.ToText(&buf, s, prefix, "", width)
+		return buf.String()
+	},
+	// date parses a feed date and formats it with layout, "" if it can't
+	// be parsed
+	"date": func(layout string, s string) string {
+		t, err := ParseTime(s)
+		if err != nil || t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	},
+}
+
+// digestTemplates are the built-in templates, "script" is the layout of
+// the wget script.
+var digestTemplates = map[string]string{
+	"script": `{{header}}
+#
+{{range .Feeds}}{{if .Items}}{{.Channel}}{{range .Items}}
+#
+{{.}}{{range .Enclosures}}{{$name := filename .}}{{if $name}}
+wget --no-clobber -O {{quote $name}} {{quote .String}}{{end}}{{end}}{{end}}
+
+{{end}}{{end}}`,
+
+	"markdown": `# {{.Title}}
+
+_{{.Generated.Format "2006-01-02 15:04"}}_
+{{range .Feeds}}{{if .Items}}
+## [{{single .Channel.Title}}]({{.Channel.Link}})
+{{range .Items}}
+### {{single .Title}}
+
+{{with date "2006-01-02" .PubDate}}{{.}}{{end}}{{if .ITunesDuration}} · {{.ITunesDuration}}{{end}}
+{{with or .Description .ITunesSummary}}
+{{strip . | truncate 600 | wrap "" 80}}{{end}}
+{{range .Enclosures}}- [{{filename .}}]({{.Url}})
+{{end}}{{end}}{{end}}{{end}}`,
+
+	"text": `{{.Title}} - {{.Generated.Format "2006-01-02 15:04"}}
+{{range .Feeds}}{{if .Items}}
+{{single .Channel.Title}}
+{{range .Items}}
+  {{single .Title}}{{with date "2006-01-02" .PubDate}} ({{.}}){{end}}{{if .ITunesDuration}} [{{.ITunesDuration}}]{{end}}
+{{with or .Description .ITunesSummary}}{{strip . | truncate 300 | wrap "    " 76}}{{end}}{{range .Enclosures}}    {{.Url}}
+{{end}}{{end}}{{end}}{{end}}`,
+}
+
+// loadDigestTemplate returns the built-in template with the given name, or
+// parses the template file at that path.
+func loadDigestTemplate(name string) (*template.Template, error) {
+
+	if text, ok := digestTemplates[name]; ok {
+		return template.New(name).Funcs(digestFuncs).Parse(text)
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(name)).Funcs(digestFuncs).Parse(string(b))
+}
+
+// writeTemplateDigest renders the new episodes of the results with tmpl.
+func writeTemplateDigest(w io.Writer, tmpl *template.Template, results []FetchResult, title string) error {
+
+	data := DigestData{Title: title, Generated: time.Now()}
+	for _, r := range results {
+		if r.Failed() {
+			continue
+		}
+		data.Feeds = append(data.Feeds, DigestFeed{Url: r.Url, Channel: r.Channel, Items: r.Episodes})
+	}
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Download is an enclosure to fetch and the file name to store it under.
+type Download struct {
+	Url      string
+	Filename string
+	// Subdir is the folder of the file inside the download folder.
+	Subdir string
+	Length int64
+	// Feed and Key identify the episode in the state database.
+	Feed string
+	Key  string
+	// Date is the publication date of the episode.
+	Date time.Time
+}
+
+// DownloadQueue collects the downloads found by the concurrent feed
+// fetchers.
+type DownloadQueue struct {
+	mu        sync.Mutex
+	downloads []Download
+}
+
+func (q *DownloadQueue) Add(d Download) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.downloads = append(q.downloads, d)
+}
+
+func (q *DownloadQueue) Downloads() []Download {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Download(nil), q.downloads...)
+}
+
+// Name is the path of the file relative to the download folder.
+func (d Download) Name() string {
+	return filepath.Join(d.Subdir, d.Filename)
+}
+
+// NewDownload builds the Download of an enclosure.
+func NewDownload(encl Enclosure) (Download, error) {
+
+	filename, err := GetFileName(encl.String())
+	if err != nil {
+		return Download{}, err
+	}
+
+	length, _ := strconv.ParseInt(encl.Length, 10, 64)
+	return Download{Url: encl.String(), Filename: filename, Length: length}, nil
+}
+
+// progressWriter counts the bytes written to a file and prints the
+// progress at most once per interval.
+type progressWriter struct {
+	filename string
+	total    int64
+	written  int64
+	last     time.Time
+	interval time.Duration
+	out      *syncWriter
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+
+	p.written += int64(len(b))
+	if time.Since(p.last) >= p.interval {
+		p.last = time.Now()
+		p.out.Printf("%s\n", p)
+	}
+	return len(b), nil
+}
+
+func (p *progressWriter) String() string {
+
+	mb := float64(p.written) / (1 << 20)
+	if p.total <= 0 {
+		return fmt.Sprintf("%8.1fMB : %s", mb, p.filename)
+	}
+	percent := float64(p.written) * 100 / float64(p.total)
+	return fmt.Sprintf("%8.1fMB : %5.1f%% : %s", mb, percent, p.filename)
+}
+
+// syncWriter serializes the progress lines of the download workers.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Printf(format string, a ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, format, a...)
+}
+
+// partInfo is stored next to a .part file and holds the validators of the
+// response the partial data came from.
+type partInfo struct {
+	Url          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the value for the If-Range header, an empty string
+// means the partial file can't be safely resumed.
+func (p partInfo) validator() string {
+	if len(p.ETag) > 0 && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+func readPartInfo(path string) (partInfo, error) {
+
+	var info partInfo
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
+
+func writePartInfo(info partInfo, path string) error {
+
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// contentRangeStart returns the first byte and the total size of a
+// "bytes first-last/total" Content-Range header. The total is -1 when
+// unknown.
+func contentRangeStart(header string) (int64, int64, error) {
+
+	var first, last int64
+	var total string
+	_, err := fmt.Sscanf(header, "bytes %d-%d/%s", &first, &last, &total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range: %q", header)
+	}
+
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		size = -1
+	}
+	return first, size, nil
+}
+
+// unsatisfiedRangeSize returns the size of the file in the "bytes */total"
+// Content-Range header of a 416 response, -1 when unknown.
+func unsatisfiedRangeSize(header string) int64 {
+
+	var size int64
+	if _, err := fmt.Sscanf(header, "bytes */%d", &size); err != nil {
+		return -1
+	}
+	return size
+}
+
+// downloadFile fetches d into dir. The body is written to a .part file
+// that is renamed once complete, so an interrupted download never leaves a
+// truncated file under the final name. An existing .part file is resumed
+// with a Range request validated with If-Range against the ETag or
+// Last-Modified of the response it was started from. The final size is
+// checked against the size reported by the server, or against the
+// enclosure length when the server doesn't report one.
+func downloadFile(ctx gocontext.Context, d Download, dir string, out *syncWriter) error {
+
+	path := filepath.Join(dir, d.Name())
+	if _, err := os.Stat(path); err == nil {
+		out.Printf("%10s : %s\n", "exists", d.Name())
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	partPath := path + ".part"
+	infoPath := partPath + ".meta"
+
+	var offset int64
+	info, err := readPartInfo(infoPath)
+	if fi, err1 := os.Stat(partPath); err == nil && err1 == nil && info.Url == d.Url && len(info.validator()) > 0 {
+		offset = fi.Size()
+	}
+
+	req, err := newRequest(ctx, "GET", d.Url)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", info.validator())
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// total is the expected size of the complete file, -1 when unknown
+	total := int64(-1)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		// a fresh download, or the file changed since the .part was written
+		offset = 0
+		if res.ContentLength >= 0 {
+			total = res.ContentLength
+		}
+		info = partInfo{
+			Url:          d.Url,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		}
+		if err := writePartInfo(info, infoPath); err != nil {
+			return err
+		}
+	case http.StatusPartialContent:
+		first, size, err := contentRangeStart(res.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if first != offset {
+			return fmt.Errorf("%s: resumed at byte %d instead of %d", d.Url, first, offset)
+		}
+		total = size
+		flags = os.O_WRONLY | os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the .part file is complete, the run that wrote it stopped before
+		// renaming it
+		if offset > 0 && unsatisfiedRangeSize(res.Header.Get("Content-Range")) == offset {
+			if err := os.Rename(partPath, path); err != nil {
+				return err
+			}
+			os.Remove(infoPath)
+			out.Printf("%10s : %s\n", "done", d.Name())
+			return nil
+		}
+		// the .part file is no longer valid, start over on the next run
+		os.Remove(partPath)
+		os.Remove(infoPath)
+		return fmt.Errorf("%s: %s, discarded %s", d.Url, res.Status, filepath.Base(partPath))
+	default:
+		return fmt.Errorf("%s: %s", d.Url, res.Status)
+	}
+
+	if total < 0 && d.Length > 0 {
+		total = d.Length
+	}
+
+	part, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	progress := &progressWriter{
+		filename: d.Name(),
+		total:    total,
+		written:  offset,
+		last:     time.Now(),
+		interval: 2 * time.Second,
+		out:      out,
+	}
+
+	if offset > 0 {
+		out.Printf("%s : resuming\n", progress)
+	}
+
+	_, err = io.Copy(part, io.TeeReader(res.Body, progress))
+	if err1 := part.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+
+	// keep the .part file on a size mismatch, the next run resumes it
+	if total >= 0 && progress.written != total {
+		return fmt.Errorf("%s: got %d bytes, expected %d", d.Name(), progress.written, total)
+	}
+
+	err = os.Rename(partPath, path)
+	if err != nil {
+		return err
+	}
+	os.Remove(infoPath)
+
+	out.Printf("%s : done\n", progress)
+	return nil
+}
+
+// DownloadAll fetches the downloads into dir with at most workers
+// concurrent transfers and returns the number of failed downloads. The
+// completed downloads are marked as downloaded in state, if not nil, with
+// their file. When ctx is done the transfers stop, leaving .part files to
+// resume, and the downloads not started yet count as failed.
+func DownloadAll(ctx gocontext.Context, downloads []Download, dir string, workers int, w io.Writer, state *StateDB) int {
+
+	out := &syncWriter{w: w}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		out.Printf("podcasts: %v\n", err)
+		return len(downloads)
+	}
+
+	// skip duplicate file names, the same episode can be in several feeds
+	var unique []Download
+	seen := make(map[string]bool)
+	for _, d := range downloads {
+		if !seen[d.Name()] {
+			seen[d.Name()] = true
+			unique = append(unique, d)
+		}
+	}
+
+	var failed int32
+	started := runPool(ctx, len(unique), workers, func(i int) {
+		d := unique[i]
+		err := downloadFile(ctx, d, dir, out)
+		if err != nil {
+			out.Printf("podcasts: %v\n", err)
+			atomic.AddInt32(&failed, 1)
+			return
+		}
+		if state != nil && len(d.Key) > 0 {
+			state.MarkDownloaded(d.Feed, d.Key, d.Name(), d.Date)
+		}
+	})
+
+	if skipped := len(unique) - started; skipped > 0 {
+		out.Printf("podcasts: %d downloads skipped: %v\n", skipped, ctx.Err())
+		return int(failed) + skipped
+	}
+
+	return int(failed)
+}
+
+// PruneDownloads removes the files of the feeds with a max setting beyond
+// their max newest downloaded episodes, and returns the number of files
+// that couldn't be removed. Only the files recorded in state are removed.
+func PruneDownloads(dir string, feeds map[string]FeedConfig, state *StateDB, w io.Writer) int {
+
+	failed := 0
+	for feed, cfg := range feeds {
+		if cfg.Max == 0 {
+			continue
+		}
+
+		keys := state.DownloadedFiles(feed)
+		if len(keys) <= cfg.Max {
+			continue
+		}
+		for _, key := range keys[cfg.Max:] {
+			name := state.Get(feed, key).File
+			err := os.Remove(filepath.Join(dir, name))
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(w, "podcasts: %v\n", err)
+				failed++
+				continue
+			}
+			state.RemoveFile(feed, key)
+			fmt.Fprintf(w, "%10s : %s\n", "removed", name)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	gocontext "context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPruneDownloads(t *testing.T) {
+
+	dir := t.TempDir()
+	state, err := OpenStateDB(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	files := []string{"a/1.mp3", "a/2.mp3", "a/3.mp3", "b.mp3"}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, name := range files[:3] {
+		state.MarkDownloaded("http://a/feed.xml", name, name, day.AddDate(0, 0, i))
+	}
+	// another feed in the same folder, without max
+	state.MarkDownloaded("http://b/feed.xml", "b", "b.mp3", day)
+
+	feeds := map[string]FeedConfig{
+		"http://a/feed.xml": {Url: "http://a/feed.xml", Max: 2, Dir: "a"},
+		"http://b/feed.xml": {Url: "http://b/feed.xml"},
+	}
+	if failed := PruneDownloads(dir, feeds, state, ioutil.Discard); failed != 0 {
+		t.Errorf("%d files not removed", failed)
+	}
+
+	for _, name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists, want := err == nil, name != "a/1.mp3"; exists != want {
+			t.Errorf("%s exists: %v, want %v", name, exists, want)
+		}
+	}
+
+	e := state.Get("http://a/feed.xml", "a/1.mp3")
+	if e == nil || !e.Downloaded || len(e.File) > 0 {
+		t.Errorf("state of the removed episode: %+v, want downloaded without file", e)
+	}
+}
+
+// episodeServer serves content with the ETag etag, the first cut requests
+// stop after half of it as if the connection broke. It records the Range
+// header of every request.
+type episodeServer struct {
+	mu      sync.Mutex
+	content []byte
+	etag    string
+	cut     int
+	ranges  []string
+}
+
+func (s *episodeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	content, etag := s.content, s.etag
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	cut := s.cut > 0
+	if cut {
+		s.cut--
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", etag)
+	if cut {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:len(content)/2])
+		return
+	}
+	http.ServeContent(w, r, "episode.mp3", time.Time{}, bytes.NewReader(content))
+}
+
+func testDownload(srv *httptest.Server, dir string) error {
+	d := Download{Url: srv.URL + "/episode.mp3", Filename: "episode.mp3"}
+	return downloadFile(gocontext.Background(), d, dir, &syncWriter{w: ioutil.Discard})
+}
+
+func checkDownloaded(t *testing.T, dir string, want []byte) {
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "episode.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("downloaded %q, want %q", b, want)
+	}
+	for _, ext := range []string{".part", ".part.meta"} {
+		if _, err := os.Stat(filepath.Join(dir, "episode.mp3"+ext)); err == nil {
+			t.Errorf("%s left behind", ext)
+		}
+	}
+}
+
+// TestDownloadResume interrupts a download and resumes it with a Range
+// request.
+func TestDownloadResume(t *testing.T) {
+
+	dir := t.TempDir()
+	s := &episodeServer{content: []byte(strings.Repeat("0123456789", 100)), etag: `"v1"`, cut: 1}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	if err := testDownload(srv, dir); err == nil {
+		t.Fatal("the interrupted download succeeded")
+	}
+	fi, err := os.Stat(filepath.Join(dir, "episode.mp3.part"))
+	if err != nil || fi.Size() != 500 {
+		t.Fatalf("part file after the interruption: %v, %v", fi, err)
+	}
+
+	if err := testDownload(srv, dir); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, dir, s.content)
+	if got := strings.Join(s.ranges, ","); got != ",bytes=500-" {
+		t.Errorf("Range headers %q, want none then bytes=500-", got)
+	}
+}
+
+// TestDownloadRestart checks that a .part file of an older version of the
+// episode is downloaded again from the start.
+func TestDownloadRestart(t *testing.T) {
+
+	dir := t.TempDir()
+	s := &episodeServer{content: []byte(strings.Repeat("a", 1000)), etag: `"v1"`, cut: 1}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	testDownload(srv, dir)
+
+	s.content, s.etag = []byte(strings.Repeat("b", 800)), `"v2"`
+	if err := testDownload(srv, dir); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, dir, s.content)
+	if got := strings.Join(s.ranges, ","); got != ",bytes=500-" {
+		t.Errorf("Range headers %q, want none then bytes=500-", got)
+	}
+}
+
+// TestDownloadCompletePart checks that a complete .part file, answered
+// with 416, is renamed into place.
+func TestDownloadCompletePart(t *testing.T) {
+
+	dir := t.TempDir()
+	s := &episodeServer{content: []byte(strings.Repeat("0123456789", 100)), etag: `"v1"`}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	d := Download{Url: srv.URL + "/episode.mp3", Filename: "episode.mp3"}
+
+	path := filepath.Join(dir, d.Name())
+	if err := ioutil.WriteFile(path+".part", s.content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePartInfo(partInfo{Url: d.Url, ETag: s.etag}, path+".part.meta"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testDownload(srv, dir); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, dir, s.content)
+	if got := strings.Join(s.ranges, ","); got != "bytes=1000-" {
+		t.Errorf("Range headers %q, want bytes=1000-", got)
+	}
+}
+
+// TestDownloadSizeMismatch checks that a download shorter than the
+// enclosure length fails and keeps its .part file.
+func TestDownloadSizeMismatch(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// no Content-Length, the enclosure length is the expected size
+		w.Write([]byte("short"))
+		w.(http.Flusher).Flush()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d := Download{Url: srv.URL + "/episode.mp3", Filename: "episode.mp3", Length: 100}
+	if err := downloadFile(gocontext.Background(), d, dir, &syncWriter{w: ioutil.Discard}); err == nil {
+		t.Fatal("the short download succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "episode.mp3")); err == nil {
+		t.Error("the short download was renamed into place")
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "episode.mp3.part")); err != nil || string(b) != "short" {
+		t.Errorf("part file %q, %v, want \"short\"", b, err)
+	}
+}
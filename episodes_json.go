@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OutputChannel is a fetched feed with its new episodes in the JSON output.
+type OutputChannel struct {
+	Url         string          `json:"url"`
+	Title       string          `json:"title"`
+	Link        string          `json:"link,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	Image       string          `json:"image,omitempty"`
+	Episodes    []OutputEpisode `json:"episodes"`
+}
+
+// OutputEpisode is an episode in the JSON output. Feed and Channel repeat
+// the url and title of its feed, so each NDJSON line stands on its own.
+type OutputEpisode struct {
+	Feed        string            `json:"feed"`
+	Channel     string            `json:"channel"`
+	Key         string            `json:"key"`
+	Guid        string            `json:"guid,omitempty"`
+	Title       string            `json:"title"`
+	Link        string            `json:"link,omitempty"`
+	PubDate     string            `json:"pub_date,omitempty"`
+	Published   *time.Time        `json:"published,omitempty"`
+	Author      string            `json:"author,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Seconds     int               `json:"duration_seconds,omitempty"`
+	Season      int               `json:"season,omitempty"`
+	Episode     int               `json:"episode,omitempty"`
+	Explicit    bool              `json:"explicit,omitempty"`
+	EpisodeType string            `json:"episode_type,omitempty"`
+	Block       bool              `json:"block,omitempty"`
+	Image       string            `json:"image,omitempty"`
+	Enclosures  []OutputEnclosure `json:"enclosures"`
+	// the podcast:* links, for the tools that fetch them
+	Transcripts []OutputLink   `json:"transcripts,omitempty"`
+	Chapters    *OutputLink    `json:"chapters,omitempty"`
+	Persons     []OutputPerson `json:"persons,omitempty"`
+	State       *OutputState   `json:"state,omitempty"`
+}
+
+type OutputEnclosure struct {
+	Url    string `json:"url"`
+	Length int64  `json:"length,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// OutputLink is a transcript or the chapters of an episode.
+type OutputLink struct {
+	Url      string `json:"url"`
+	Type     string `json:"type,omitempty"`
+	Language string `json:"language,omitempty"`
+	Rel      string `json:"rel,omitempty"`
+}
+
+type OutputPerson struct {
+	Name  string `json:"name"`
+	Role  string `json:"role,omitempty"`
+	Group string `json:"group,omitempty"`
+	Image string `json:"image,omitempty"`
+	Link  string `json:"link,omitempty"`
+}
+
+// OutputState is the state of an episode after the run.
+type OutputState struct {
+	Status     string    `json:"status"`
+	FirstSeen  time.Time `json:"first_seen"`
+	Downloaded bool      `json:"downloaded"`
+	Played     bool      `json:"played"`
+	Skipped    bool      `json:"skipped"`
+}
+
+func newOutputChannel(r FetchResult, state *StateDB) OutputChannel {
+
+	c := r.Channel
+	oc := OutputChannel{
+		Url:         r.Url,
+		Title:       strings.TrimSpace(c.Title),
+		Link:        strings.TrimSpace(c.Link),
+		Description: strings.TrimSpace(StripTags(c.Description)),
+		Author:      strings.TrimSpace(c.ITunesAuthor),
+		Image:       c.ITunesImage.Href,
+		Episodes:    []OutputEpisode{},
+	}
+	if len(oc.Description) == 0 {
+		oc.Description = strings.TrimSpace(StripTags(c.ITunesSummary))
+	}
+
+	for _, item := range r.Episodes {
+		oc.Episodes = append(oc.Episodes, newOutputEpisode(r.Url, oc.Title, item, state))
+	}
+	return oc
+}
+
+func newOutputEpisode(feed string, channel string, item Item, state *StateDB) OutputEpisode {
+
+	author := item.Author
+	if len(strings.TrimSpace(author)) == 0 {
+		author = item.ITunesAuthor
+	}
+
+	desc := item.Description
+	if len(strings.TrimSpace(desc)) == 0 {
+		desc = item.ITunesSummary
+	}
+
+	e := OutputEpisode{
+		Feed:        feed,
+		Channel:     channel,
+		Key:         ItemKey(item),
+		Guid:        strings.TrimSpace(item.Guid.Value),
+		Title:       strings.TrimSpace(item.Title),
+		Link:        strings.TrimSpace(item.Link),
+		PubDate:     strings.TrimSpace(item.PubDate),
+		Author:      strings.TrimSpace(author),
+		Description: strings.TrimSpace(StripTags(desc)),
+		Seconds:     item.ITunesDuration.Seconds(),
+		Season:      int(item.ITunesSeason),
+		Episode:     int(item.ITunesEpisode),
+		Explicit:    bool(item.ITunesExplicit),
+		EpisodeType: strings.TrimSpace(item.ITunesEpisodeType),
+		Block:       bool(item.ITunesBlock),
+		Image:       strings.TrimSpace(item.ITunesImage.Href),
+		Enclosures:  []OutputEnclosure{},
+	}
+
+	if t, err := ParseTime(item.PubDate); err == nil && !t.IsZero() {
+		t = t.UTC()
+		e.Published = &t
+	}
+
+	for _, encl := range item.Enclosures {
+		length, _ := strconv.ParseInt(strings.TrimSpace(encl.Length), 10, 64)
+		e.Enclosures = append(e.Enclosures, OutputEnclosure{Url: encl.String(), Length: length, Type: encl.Type})
+	}
+
+	for _, t := range item.PodcastTranscripts {
+		e.Transcripts = append(e.Transcripts, OutputLink{Url: strings.TrimSpace(t.Url), Type: t.Type, Language: t.Language, Rel: t.Rel})
+	}
+	if c := item.PodcastChapters; c != nil && len(strings.TrimSpace(c.Url)) > 0 {
+		e.Chapters = &OutputLink{Url: strings.TrimSpace(c.Url), Type: c.Type}
+	}
+	for _, p := range item.PodcastPersons {
+		e.Persons = append(e.Persons, OutputPerson{Name: strings.TrimSpace(p.Name), Role: p.Role, Group: p.Group, Image: p.Img, Link: p.Href})
+	}
+
+	if s := state.Get(feed, e.Key); s != nil {
+		e.State = &OutputState{
+			Status:     s.Status(),
+			FirstSeen:  s.FirstSeen,
+			Downloaded: s.Downloaded,
+			Played:     s.Played,
+			Skipped:    s.Skipped,
+		}
+	}
+	return e
+}
+
+// writeEpisodesJSON writes the feeds that were fetched, with their new
+// episodes, as one JSON document.
+func writeEpisodesJSON(w io.Writer, results []FetchResult, state *StateDB) error {
+
+	v := struct {
+		Generated time.Time       `json:"generated"`
+		Channels  []OutputChannel `json:"channels"`
+	}{Generated: time.Now().UTC(), Channels: []OutputChannel{}}
+
+	for _, r := range results {
+		if r.Failed() {
+			continue
+		}
+		v.Channels = append(v.Channels, newOutputChannel(r, state))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeEpisodesNDJSON writes the new episodes as one JSON object per line.
+func writeEpisodesNDJSON(w io.Writer, results []FetchResult, state *StateDB) error {
+
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if r.Failed() {
+			continue
+		}
+		for _, e := range newOutputChannel(r, state).Episodes {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
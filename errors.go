@@ -0,0 +1,105 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// exitPartialFailure is the exit code when some of the feeds or downloads
+// failed but not all of them.
+const exitPartialFailure = 3
+
+// HTTPStatusError is returned when a feed is answered with a status other
+// than 200 OK or 304 Not Modified.
+type HTTPStatusError struct {
+	Url        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Url, e.Status)
+}
+
+// ParseError is returned when a feed body can't be decoded.
+type ParseError struct {
+	Url string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Url, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// NetworkError is returned when the connection fails or breaks while the
+// feed is read.
+type NetworkError struct {
+	Url string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Url, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// TimeoutError is returned when the feed server doesn't answer in time.
+type TimeoutError struct {
+	Url string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: timeout: %v", e.Url, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// networkError wraps an error of the http client into a TimeoutError or a
+// NetworkError. An expired context deadline is a net.Error too.
+func networkError(feed_url string, err error) error {
+
+	// the url is already in our error message
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &TimeoutError{Url: feed_url, Err: err}
+	}
+	return &NetworkError{Url: feed_url, Err: err}
+}
+
+// errorKind is the short name of an error for the summary table.
+func errorKind(err error) string {
+
+	var statusErr *HTTPStatusError
+	var parseErr *ParseError
+	var networkErr *NetworkError
+	var timeoutErr *TimeoutError
+
+	switch {
+	case errors.Is(err, gocontext.Canceled):
+		return "CANCELED"
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("HTTP %d", statusErr.StatusCode)
+	case errors.As(err, &parseErr):
+		return "PARSE ERROR"
+	case errors.As(err, &timeoutErr):
+		return "TIMEOUT"
+	case errors.As(err, &networkErr):
+		return "NETWORK ERROR"
+	case errors.Is(err, gocontext.DeadlineExceeded):
+		// not fetched before -deadline expired
+		return "SKIPPED"
+	}
+	return "ERROR"
+}
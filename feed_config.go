@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FeedConfig are the settings of a feed, written in its [feed."url"] table
+// of the config file or after its url in the feed list:
+//
+//	https://example.com/feed.xml include="(?i)interview" exclude=trailer max=3 type=audio/mpeg dir=example
+//	https://example.com/other.xml disabled
+//
+// Values with spaces are written in double quotes.
+type FeedConfig struct {
+	Url string
+	// Include and Exclude select the episodes by title when not nil.
+	Include *regexp.Regexp
+	Exclude *regexp.Regexp
+	// Max is the number of newest downloaded episodes of the feed kept in
+	// the download folder, the older files are removed, 0 for no limit. A
+	// run selects at most Max episodes.
+	Max int
+	// Type is the preferred MIME type of the enclosures, such as
+	// "audio/mpeg" or "video/*".
+	Type string
+	// Dir is the folder of the downloads of the feed, relative to the
+	// download folder.
+	Dir      string
+	Disabled bool
+}
+
+// errFeedDisabled is the error of the feeds that are not fetched.
+var errFeedDisabled = errors.New("disabled")
+
+// ParseFeedLine parses a line of the feed list: a feed url followed by its
+// settings. The settings of the line are added to the ones of the feed in
+// defaults, which may be nil.
+func ParseFeedLine(line string, defaults map[string]FeedConfig) (FeedConfig, error) {
+
+	fields, err := splitFields(line)
+	if err != nil {
+		return FeedConfig{}, err
+	}
+	if len(fields) == 0 {
+		return FeedConfig{}, fmt.Errorf("no feed url")
+	}
+
+	cfg, ok := defaults[fields[0]]
+	if !ok {
+		cfg = FeedConfig{Url: fields[0]}
+	}
+	for _, field := range fields[1:] {
+
+		key, value := field, ""
+		if i := strings.Index(field, "="); i >= 0 {
+			key, value = field[:i], field[i+1:]
+		}
+		if err := cfg.Set(key, value); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// Set sets the setting key of the feed, as written in the feed list or
+// the config file.
+func (cfg *FeedConfig) Set(key string, value string) error {
+
+	switch key {
+	case "include", "exclude":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if key == "include" {
+			cfg.Include = re
+		} else {
+			cfg.Exclude = re
+		}
+	case "max":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max: %q is not a number of episodes", value)
+		}
+		cfg.Max = n
+	case "type":
+		cfg.Type = strings.ToLower(value)
+	case "dir":
+		dir := filepath.Clean(value)
+		if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("dir: %q is not a folder inside the download folder", value)
+		}
+		cfg.Dir = dir
+	case "disabled":
+		cfg.Disabled = len(value) == 0 || value == "true" || value == "yes"
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	return nil
+}
+
+// splitFields splits a line on whitespace, keeping the text between double
+// quotes in one field without the quotes.
+func splitFields(line string) ([]string, error) {
+
+	var fields []string
+	var field strings.Builder
+	inField, quoted := false, false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted, inField = !quoted, true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("missing closing quote")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// ReadFeedConfigs reads the settings of the feeds in the feed list file,
+// keyed by url, on top of the ones in defaults.
+func ReadFeedConfigs(path string, defaults map[string]FeedConfig) (map[string]FeedConfig, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	configs := make(map[string]FeedConfig)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		curr_line := strings.Trim(scanner.Text(), "\t ")
+		match, _ := regexp.MatchString(HTTPS_REGEX, curr_line)
+		if match != true {
+			continue
+		}
+
+		cfg, err := ParseFeedLine(curr_line, defaults)
+		if err != nil {
+			return configs, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		configs[cfg.Url] = cfg
+	}
+	return configs, scanner.Err()
+}
+
+// Match reports whether the title of the item passes the include and
+// exclude filters.
+func (cfg FeedConfig) Match(item Item) bool {
+
+	title := strings.TrimSpace(item.Title)
+	if cfg.Include != nil && !cfg.Include.MatchString(title) {
+		return false
+	}
+	if cfg.Exclude != nil && cfg.Exclude.MatchString(title) {
+		return false
+	}
+	return true
+}
+
+// Enclosures returns the enclosures of the item to download: the first one,
+// including the podcast:alternateEnclosure sources, of the preferred type,
+// or all of them when there is no preferred type or none has it.
+func (cfg FeedConfig) Enclosures(item Item) []Enclosure {
+
+	if len(cfg.Type) == 0 {
+		return item.Enclosures
+	}
+
+	candidates := append([]Enclosure(nil), item.Enclosures...)
+	for _, alt := range item.PodcastAlternateEnclosures {
+		candidates = append(candidates, alt.Enclosures()...)
+	}
+
+	for _, encl := range candidates {
+		if matchType(cfg.Type, encl.Type) {
+			return []Enclosure{encl}
+		}
+	}
+	return item.Enclosures
+}
+
+// matchType reports whether the MIME type t, with or without parameters,
+// matches pattern, which may end in "/*".
+func matchType(pattern string, t string) bool {
+
+	if mt, _, err := mime.ParseMediaType(t); err == nil {
+		t = mt
+	}
+	t = strings.ToLower(strings.TrimSpace(t))
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(t, strings.TrimSuffix(pattern, "*"))
+	}
+	return t == pattern
+}
+
+// feedDir is the download folder of a feed with the dir setting subdir,
+// "" if dir is "".
+func feedDir(dir string, subdir string) string {
+	if len(dir) == 0 {
+		return ""
+	}
+	return filepath.Join(dir, subdir)
+}
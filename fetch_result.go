@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Cache status of a FetchResult.
+const (
+	CacheNone = ""
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// FetchResult is the outcome of fetching one feed.
+type FetchResult struct {
+	Url      string
+	Title    string
+	Duration time.Duration
+	// Bytes is the size of the feed's part of the script.
+	Bytes int
+	// Items is the number of items in the feed, NewItems the number of
+	// them selected for the script and the downloads.
+	Items    int
+	NewItems int
+	Cache    string
+	Sha1     string
+	// Subdir is the dir setting of the feed.
+	Subdir string
+	Err    error
+	// Channel is the parsed feed and Episodes the selected items.
+	Channel  Channel
+	Episodes []Item
+}
+
+func (r FetchResult) Failed() bool {
+	return r.Err != nil
+}
+
+// String is the line of the result in the summary table.
+func (r FetchResult) String() string {
+
+	url_str := r.Url
+	if len(url_str) > 50 {
+		url_str = url_str[:50]
+	}
+
+	cache_status := r.Cache
+	if len(cache_status) == 0 {
+		cache_status = "-"
+	}
+
+	if r.Err != nil {
+		msg := r.Err.Error()
+		if strings.HasPrefix(msg, r.Url+": ") {
+			msg = msg[len(r.Url)+2:]
+		}
+		if len(msg) > 25 {
+			msg = msg[:25]
+		}
+		return fmt.Sprintf("%5.2fs : %-6s : %-5s : %20s : %-25s : %s", r.Duration.Seconds(), "-", cache_status, errorKind(r.Err), msg, url_str)
+	}
+
+	channel_title := r.Title
+	if len(channel_title) > 25 {
+		channel_title = channel_title[:25]
+	}
+
+	sha := r.Sha1
+	if len(sha) > 20 {
+		sha = sha[:20]
+	}
+
+	return fmt.Sprintf("%5.2fs : %-6d : %-5s : %20s : %-25s : %s", r.Duration.Seconds(), r.Bytes, cache_status, sha, channel_title, url_str)
+}
+
+// MarshalJSON leaves out the channel and the episodes and writes the
+// duration in seconds and the error as a string with its kind.
+func (r FetchResult) MarshalJSON() ([]byte, error) {
+
+	v := struct {
+		Url       string  `json:"url"`
+		Title     string  `json:"title,omitempty"`
+		Seconds   float64 `json:"seconds"`
+		Bytes     int     `json:"bytes"`
+		Items     int     `json:"items"`
+		NewItems  int     `json:"new_items"`
+		Cache     string  `json:"cache,omitempty"`
+		Sha1      string  `json:"sha1,omitempty"`
+		ErrorKind string  `json:"error_kind,omitempty"`
+		Error     string  `json:"error,omitempty"`
+	}{
+		Url:      r.Url,
+		Title:    r.Title,
+		Seconds:  r.Duration.Seconds(),
+		Bytes:    r.Bytes,
+		Items:    r.Items,
+		NewItems: r.NewItems,
+		Cache:    r.Cache,
+		Sha1:     r.Sha1,
+	}
+
+	if r.Err != nil {
+		v.ErrorKind = errorKind(r.Err)
+		v.Error = r.Err.Error()
+	}
+
+	return json.Marshal(v)
+}
+
+// countFailed returns the number of failed results.
+func countFailed(results []FetchResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Failed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// exitStatus is the exit code for the results of an update: 1 when every
+// feed failed, exitPartialFailure when some of them or some of the
+// downloads failed.
+func exitStatus(results []FetchResult, failedDownloads int) int {
+
+	failed := countFailed(results)
+	switch {
+	case len(results) > 0 && failed == len(results):
+		return 1
+	case failed > 0 || failedDownloads > 0:
+		return exitPartialFailure
+	}
+	return 0
+}
+
+// printTableHeader prints the header of the summary table.
+func printTableHeader(w io.Writer) {
+
+	fmt.Fprintf(w, "%s\n", constructPodcastHeader(WIDTH_HEADER))
+
+	// 1234567890
+	//       secs : nbytes : cache :                 sha1 : Title                     : URL
+	fmt.Fprintf(w, "%6s : %6s : %-5s : %20s : %-25s : %s\n", "secs", "nbytes", "cache", "sha1", "Title", "URL")
+}
+
+// printTableFooter prints the lines of the feeds that were skipped, which
+// were never reported, and the totals of the summary table.
+func printTableFooter(w io.Writer, results []FetchResult, elapsed time.Duration) {
+
+	skipped := 0
+	for _, r := range results {
+		if r.Failed() && r.Duration == 0 {
+			fmt.Fprintln(w, r)
+			skipped++
+		}
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(w, "\n%d feeds skipped", skipped)
+	}
+	if failed := countFailed(results); failed > 0 {
+		fmt.Fprintf(w, "\n%d of %d feeds failed", failed, len(results))
+	}
+	fmt.Fprintf(w, "\n%5.2fs elapsed\n\n", elapsed.Seconds())
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ITunesChannel holds the itunes:* elements of an RSS channel.
+// https://help.apple.com/itc/podcasts_connect/#/itcb54353390
+type ITunesChannel struct {
+	ITunesAuthor   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author,omitempty"`
+	ITunesSummary  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary,omitempty"`
+	ITunesImage    ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image,omitempty"`
+	ITunesExplicit ITunesBool  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit,omitempty"`
+	ITunesBlock    ITunesBool  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd block,omitempty"`
+}
+
+// ITunesItem holds the itunes:* elements of an RSS item.
+type ITunesItem struct {
+	ITunesAuthor      string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author,omitempty"`
+	ITunesSummary     string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary,omitempty"`
+	ITunesImage       ITunesImage    `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image,omitempty"`
+	ITunesDuration    ITunesDuration `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration,omitempty"`
+	ITunesEpisode     ITunesNumber   `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode,omitempty"`
+	ITunesSeason      ITunesNumber   `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season,omitempty"`
+	ITunesEpisodeType string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episodeType,omitempty"`
+	ITunesExplicit    ITunesBool     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit,omitempty"`
+	ITunesBlock       ITunesBool     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd block,omitempty"`
+}
+
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// MarshalXML leaves out an image without href.
+func (img ITunesImage) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(img.Href) == 0 {
+		return nil
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "href"}, Value: img.Href})
+	return e.EncodeElement("", start)
+}
+
+// ITunesBool is a yes/no flag such as itunes:explicit or itunes:block.
+// "yes", "true" and "explicit" are true, anything else is false.
+type ITunesBool bool
+
+func (b *ITunesBool) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true", "explicit":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+func (b ITunesBool) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(b.String(), start)
+}
+
+func (b ITunesBool) String() string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// ITunesNumber is an episode or season number. Values that are not
+// numbers are ignored instead of failing the whole feed.
+type ITunesNumber int
+
+func (n *ITunesNumber) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+
+	*n = parseNumber(s)
+	return nil
+}
+
+func parseNumber(s string) ITunesNumber {
+
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return ITunesNumber(i)
+}
+
+// ITunesDuration is the length of an episode. Feeds use either a number of
+// seconds or one of the HH:MM:SS, H:MM:SS, MM:SS and M:SS forms.
+type ITunesDuration time.Duration
+
+func (du *ITunesDuration) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+
+	duration, err := ParseDuration(s)
+	if err != nil {
+		*du = 0
+		return nil
+	}
+	*du = ITunesDuration(duration)
+	return nil
+}
+
+// MarshalXML writes the duration as a number of seconds.
+func (du ITunesDuration) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(du.Seconds(), start)
+}
+
+func (du ITunesDuration) Seconds() int {
+	return int(time.Duration(du).Seconds())
+}
+
+func (du ITunesDuration) String() string {
+	secs := du.Seconds()
+	return fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+}
+
+// ParseDuration parses an itunes:duration value.
+func ParseDuration(s string) (time.Duration, error) {
+
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	// some feeds write fractional seconds
+	if i := strings.Index(s, "."); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+
+	secs := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration: %q", s)
+		}
+		secs = secs*60 + n
+	}
+
+	return time.Duration(secs) * time.Second, nil
+}
+
+// episodeNumber formats the season and episode numbers as S01E02, leaving
+// out the parts that are not set.
+func (it ITunesItem) episodeNumber() string {
+
+	var s string
+	if it.ITunesSeason > 0 {
+		s += fmt.Sprintf("S%02d", int(it.ITunesSeason))
+	}
+	if it.ITunesEpisode > 0 {
+		s += fmt.Sprintf("E%02d", int(it.ITunesEpisode))
+	}
+	return s
+}
+
+// ITunesLines returns the "# Key: value" comment lines for the iTunes
+// fields that are set.
+func (it ITunesItem) ITunesLines() []string {
+
+	var lines []string
+	if it.ITunesDuration > 0 {
+		lines = append(lines, "# Duration: "+it.ITunesDuration.String())
+	}
+	if episode := it.episodeNumber(); len(episode) > 0 {
+		lines = append(lines, "# Episode: "+episode)
+	}
+	if len(it.ITunesEpisodeType) > 0 {
+		lines = append(lines, "# EpisodeType: "+strings.TrimSpace(it.ITunesEpisodeType))
+	}
+	if len(it.ITunesAuthor) > 0 {
+		lines = append(lines, "# Author: "+strings.TrimSpace(it.ITunesAuthor))
+	}
+	if len(it.ITunesImage.Href) > 0 {
+		lines = append(lines, "# Image: "+it.ITunesImage.Href)
+	}
+	if it.ITunesExplicit {
+		lines = append(lines, "# Explicit: "+it.ITunesExplicit.String())
+	}
+	if it.ITunesBlock {
+		lines = append(lines, "# Block: "+it.ITunesBlock.String())
+	}
+	return lines
+}
+
+// ITunesLines returns the "# Key: value" comment lines for the iTunes
+// fields that are set.
+func (ic ITunesChannel) ITunesLines() []string {
+
+	var lines []string
+	if len(ic.ITunesAuthor) > 0 {
+		lines = append(lines, "# Author: "+strings.TrimSpace(ic.ITunesAuthor))
+	}
+	if len(ic.ITunesImage.Href) > 0 {
+		lines = append(lines, "# Image: "+ic.ITunesImage.Href)
+	}
+	if ic.ITunesExplicit {
+		lines = append(lines, "# Explicit: "+ic.ITunesExplicit.String())
+	}
+	if ic.ITunesBlock {
+		lines = append(lines, "# Block: "+ic.ITunesBlock.String())
+	}
+	return lines
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// JSONFeed is a feed in the JSON Feed 1.0 or 1.1 format.
+// https://www.jsonfeed.org/version/1.1/
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageUrl string         `json:"home_page_url"`
+	FeedUrl     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Authors     []JSONAuthor   `json:"authors"`
+	Author      *JSONAuthor    `json:"author"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	Id            string           `json:"id"`
+	Url           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHtml   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []JSONAuthor     `json:"authors"`
+	Author        *JSONAuthor      `json:"author"`
+	Attachments   []JSONAttachment `json:"attachments"`
+}
+
+type JSONAuthor struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type JSONAttachment struct {
+	Url               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	Title             string  `json:"title"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// authorName returns the first author name, preferring the 1.1 "authors"
+// array over the deprecated 1.0 "author" object.
+func authorName(authors []JSONAuthor, author *JSONAuthor) string {
+	if len(authors) > 0 {
+		return authors[0].Name
+	}
+	if author != nil {
+		return author.Name
+	}
+	return ""
+}
+
+// Channel maps the JSON feed onto the same model used for RSS feeds.
+func (f JSONFeed) Channel() Channel {
+
+	channel := Channel{
+		Title:       strings.TrimSpace(f.Title),
+		Link:        f.HomePageUrl,
+		Description: f.Description,
+	}
+
+	for _, entry := range f.Items {
+		channel.Items = append(channel.Items, entry.Item())
+	}
+
+	if len(channel.Items) > 0 {
+		channel.PubDate = channel.Items[0].PubDate
+	}
+
+	return channel
+}
+
+// Item maps a JSON feed item onto an RSS item. Attachments become the
+// enclosures of the item.
+func (i JSONFeedItem) Item() Item {
+
+	item := Item{
+		Title:       i.Title,
+		Link:        i.Url,
+		Guid:        Guid{Value: i.Id, IsPermaLink: "false"},
+		PubDate:     i.DatePublished,
+		Author:      authorName(i.Authors, i.Author),
+		Description: i.Summary,
+	}
+
+	if len(item.PubDate) == 0 {
+		item.PubDate = i.DateModified
+	}
+
+	if len(item.Description) == 0 {
+		item.Description = i.ContentHtml
+	}
+
+	if len(item.Description) == 0 {
+		item.Description = i.ContentText
+	}
+
+	for _, attachment := range i.Attachments {
+		enclosure := Enclosure{
+			Url:  attachment.Url,
+			Type: attachment.MimeType,
+		}
+		if attachment.SizeInBytes > 0 {
+			enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+		}
+		item.Enclosures = append(item.Enclosures, enclosure)
+
+		if item.ITunesDuration == 0 && attachment.DurationInSeconds > 0 {
+			item.ITunesDuration = ITunesDuration(time.Duration(attachment.DurationInSeconds) * time.Second)
+		}
+	}
+
+	return item
+}
+
+// isJSONFeed reports whether the body looks like a JSON document, either
+// from the Content-Type header or from its first non blank character.
+func isJSONFeed(body []byte, contentType string) bool {
+
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+func parseJSONFeed(body []byte) (Channel, error) {
+
+	var feed JSONFeed
+	err := json.Unmarshal(body, &feed)
+	if err != nil {
+		return Channel{}, err
+	}
+
+	if !strings.HasPrefix(feed.Version, jsonFeedVersionPrefix) {
+		return Channel{}, fmt.Errorf("unsupported JSON feed version: %q", feed.Version)
+	}
+
+	return feed.Channel(), nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	gocontext "context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Opml struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Head    OpmlHead  `xml:"head"`
+	Body    []Outline `xml:"body>outline"`
+}
+
+type OpmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XmlUrl   string    `xml:"xmlUrl,attr,omitempty"`
+	HtmlUrl  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// feeds returns the outlines with a feed url, including the ones nested in
+// folders.
+func (o Outline) feeds() []Outline {
+
+	var feeds []Outline
+	if len(o.XmlUrl) > 0 {
+		feeds = append(feeds, o)
+	}
+	for _, child := range o.Outlines {
+		feeds = append(feeds, child.feeds()...)
+	}
+	return feeds
+}
+
+func (o Outline) title() string {
+	if len(o.Title) > 0 {
+		return strings.TrimSpace(o.Title)
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// ReadOpml returns the feed outlines of an OPML file.
+func ReadOpml(path string) ([]Outline, error) {
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc Opml
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var feeds []Outline
+	for _, outline := range doc.Body {
+		feeds = append(feeds, outline.feeds()...)
+	}
+	return feeds, nil
+}
+
+// readFeedTitles returns the titles stored in the feed list file. A title is
+// a "# title" comment on the line before the feed url.
+func readFeedTitles(path string) (map[string]string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	titles := make(map[string]string)
+	title := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		curr_line := strings.Trim(scanner.Text(), "\t ")
+		if strings.HasPrefix(curr_line, "#") {
+			title = strings.TrimSpace(strings.TrimPrefix(curr_line, "#"))
+			continue
+		}
+		match, _ := regexp.MatchString(HTTPS_REGEX, curr_line)
+		if match == true && len(title) > 0 {
+			titles[strings.Fields(curr_line)[0]] = title
+		}
+		title = ""
+	}
+	return titles, scanner.Err()
+}
+
+// ImportOpml adds the feeds of an OPML file that are not in the feed list
+// yet, each preceded by its title as a comment, and returns the number of
+// feeds added.
+func ImportOpml(opmlPath string, feedPath string) (int, error) {
+
+	outlines, err := ReadOpml(opmlPath)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := readLines(feedPath)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	seen := make(map[string]bool)
+	for _, line := range existing {
+		seen[line] = true
+	}
+
+	var text string
+	added := 0
+	for _, outline := range outlines {
+		feedUrl := strings.TrimSpace(outline.XmlUrl)
+		match, _ := regexp.MatchString(HTTPS_REGEX, feedUrl)
+		if match == false || seen[feedUrl] {
+			continue
+		}
+		seen[feedUrl] = true
+
+		if title := singleLine(outline.title()); len(title) > 0 {
+			text += "\n# " + title
+		}
+		text += "\n" + feedUrl
+		added++
+	}
+
+	if added == 0 {
+		return 0, nil
+	}
+
+	b, err := ioutil.ReadFile(feedPath)
+	if os.IsNotExist(err) {
+		return added, writeText(strings.TrimPrefix(text, "\n"), feedPath)
+	}
+	if len(b) == 0 || b[len(b)-1] == '\n' {
+		text = strings.TrimPrefix(text, "\n")
+	}
+	return added, appendText(text, feedPath)
+}
+
+// ExportOpml writes the feed list as an OPML document. The titles come from
+// the feeds themselves, fetched with at most opts.Concurrency requests at a
+// time each limited by opts.Timeout, or from the feed list file when a feed
+// can't be fetched before ctx is done.
+func ExportOpml(ctx gocontext.Context, feeds []string, feedPath string, w io.Writer, opts FetchOptions) error {
+
+	titles, err := readFeedTitles(feedPath)
+	if err != nil {
+		titles = make(map[string]string)
+	}
+
+	fetch := func(feedUrl string) (Channel, error) {
+		ctx := ctx
+		if opts.Timeout > 0 {
+			var cancel gocontext.CancelFunc
+			ctx, cancel = gocontext.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		channel, _, err := FetchPodcastData(ctx, feedUrl, opts.Cache)
+		return channel, err
+	}
+
+	// the feeds not fetched before ctx is done keep the titles of the list
+	outlines := make([]Outline, len(feeds))
+	for i, feedUrl := range feeds {
+		outlines[i] = Outline{Type: "rss", XmlUrl: feedUrl, Text: titles[feedUrl]}
+	}
+	runPool(ctx, len(feeds), opts.Concurrency, func(i int) {
+		channel, err := fetch(feeds[i])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+			return
+		}
+		if title := strings.TrimSpace(channel.Title); len(title) > 0 {
+			outlines[i].Text = title
+		}
+		outlines[i].HtmlUrl = strings.TrimSpace(channel.Link)
+	})
+
+	for i := range outlines {
+		if len(outlines[i].Text) == 0 {
+			outlines[i].Text = outlines[i].XmlUrl
+		}
+		outlines[i].Title = outlines[i].Text
+	}
+
+	doc := Opml{
+		Version: "2.0",
+		Head: OpmlHead{
+			Title:       "Podcasts",
+			DateCreated: time.Now().UTC().Format(time.RFC1123),
+		},
+		Body: outlines,
+	}
+
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, b)
+	return err
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FeedOptions describes the merged feed written by writeMergedFeed.
+type FeedOptions struct {
+	Title string
+	Link  string
+	// Dir is the download folder, the enclosures found there point at the
+	// local copies.
+	Dir string
+	// BaseUrl is the url the download folder is served at. Without it the
+	// local copies are file:// urls.
+	BaseUrl string
+}
+
+// mergedFeed returns an RSS 2.0 feed with the new episodes of every feed
+// that was fetched, newest first.
+func mergedFeed(results []FetchResult, opts FeedOptions) Rss2 {
+
+	title := opts.Title
+	if len(title) == 0 {
+		title = "Podcasts"
+	}
+
+	channel := Channel{
+		Title:         title,
+		Link:          opts.Link,
+		LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	// RSS 2.0 requires a link, the download folder when it isn't served
+	if len(channel.Link) == 0 {
+		channel.Link = folderUrl(opts.Dir)
+	}
+
+	type dated struct {
+		item Item
+		date time.Time
+	}
+
+	var items []dated
+	feeds := 0
+	for _, r := range results {
+		if r.Failed() {
+			continue
+		}
+		feeds++
+
+		for _, item := range r.Episodes {
+
+			// itunes:title too, the apps that prefer it would hide the show
+			item.Title = strings.TrimSpace(item.Title)
+			item.ITunesTitle = strings.TrimSpace(item.ITunesTitle)
+			if show := strings.TrimSpace(r.Title); len(show) > 0 {
+				item.Title = show + " - " + item.Title
+				if len(item.ITunesTitle) > 0 {
+					item.ITunesTitle = show + " - " + item.ITunesTitle
+				}
+			}
+			// the enclosure url or the title, not the url of the episode
+			if len(strings.TrimSpace(item.Guid.Value)) == 0 {
+				item.Guid = Guid{Value: ItemKey(item), IsPermaLink: "false"}
+			}
+			if len(strings.TrimSpace(item.ITunesAuthor)) == 0 {
+				item.ITunesAuthor = r.Channel.ITunesAuthor
+			}
+			if len(item.ITunesImage.Href) == 0 {
+				item.ITunesImage = r.Channel.ITunesImage
+			}
+
+			item, date := feedItem(item, r.Subdir, opts)
+			items = append(items, dated{item, date})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].date.After(items[j].date)
+	})
+	for _, d := range items {
+		channel.Items = append(channel.Items, d.item)
+	}
+
+	channel.Description = fmt.Sprintf("New episodes of %d podcasts", feeds)
+	return Rss2{Version: "2.0", Channel: channel}
+}
+
+// feedItem returns the item with its enclosures pointing at the local
+// copies in the subdir folder and its date in the RSS format, and the
+// parsed date.
+func feedItem(item Item, subdir string, opts FeedOptions) (Item, time.Time) {
+
+	// don't share the enclosures with the fetched channel
+	item.Enclosures = append([]Enclosure(nil), item.Enclosures...)
+	for k, encl := range item.Enclosures {
+		item.Enclosures[k].Url = localEnclosureUrl(encl, subdir, opts)
+	}
+
+	// atom and json feeds have RFC 3339 dates
+	date, err := ParseTime(item.PubDate)
+	if err == nil && !date.IsZero() {
+		item.PubDate = date.Format(time.RFC1123Z)
+	}
+	return item, date
+}
+
+// podcastFeed returns the feed of one podcast with only its selected
+// episodes.
+func podcastFeed(r FetchResult, opts FeedOptions) Rss2 {
+
+	channel := r.Channel
+	// the rel="self" link of the podcast is not the url of this feed
+	channel.AtomLinks = nil
+	channel.Items = nil
+	for _, item := range r.Episodes {
+		item, _ = feedItem(item, r.Subdir, opts)
+		channel.Items = append(channel.Items, item)
+	}
+	return Rss2{Version: "2.0", Channel: channel}
+}
+
+// localEnclosureUrl is the url of the downloaded copy of the enclosure in
+// the subdir folder, or its own url if it wasn't downloaded.
+func localEnclosureUrl(encl Enclosure, subdir string, opts FeedOptions) string {
+
+	location := enclosureLocation(encl, feedDir(opts.Dir, subdir))
+	if location == encl.String() {
+		return encl.Url
+	}
+
+	if len(opts.BaseUrl) == 0 {
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(location)}
+		return u.String()
+	}
+
+	segments := []string{strings.TrimSuffix(opts.BaseUrl, "/")}
+	for _, segment := range strings.Split(filepath.ToSlash(filepath.Join(subdir, filepath.Base(location))), "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	return strings.Join(segments, "/")
+}
+
+// folderUrl is the file:// url of dir, the working directory if empty.
+func folderUrl(dir string) string {
+
+	if len(dir) == 0 {
+		dir = "."
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(dir) + "/"}
+	return u.String()
+}
+
+// writeMergedFeed writes the merged feed of the results as RSS 2.0.
+func writeMergedFeed(w io.Writer, results []FetchResult, opts FeedOptions) error {
+	return writeRss(w, mergedFeed(results, opts))
+}
+
+func writeRss(w io.Writer, rss Rss2) error {
+
+	b, err := xml.MarshalIndent(rss, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
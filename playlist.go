@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PlaylistEntry is an enclosure of a new episode in a playlist.
+type PlaylistEntry struct {
+	// Location is the enclosure url, or the path of the downloaded file.
+	Location string
+	Title    string
+	// Seconds is the itunes:duration of the episode, -1 if unknown.
+	Seconds int
+}
+
+// playlistEntries returns one entry per enclosure of the new episodes. When
+// dir is not empty, the enclosures that were downloaded there point at the
+// local files and the others at their urls.
+func playlistEntries(results []FetchResult, dir string) []PlaylistEntry {
+
+	var entries []PlaylistEntry
+	for _, r := range results {
+		if r.Failed() {
+			continue
+		}
+
+		for _, item := range r.Episodes {
+
+			title := singleLine(strings.TrimSpace(item.Title))
+			if channel := singleLine(strings.TrimSpace(r.Title)); len(channel) > 0 {
+				title = channel + " - " + title
+			}
+
+			seconds := item.ITunesDuration.Seconds()
+			if seconds == 0 {
+				seconds = -1
+			}
+
+			for _, encl := range item.Enclosures {
+				entries = append(entries, PlaylistEntry{
+					Location: enclosureLocation(encl, feedDir(dir, r.Subdir)),
+					Title:    title,
+					Seconds:  seconds,
+				})
+			}
+		}
+	}
+	return entries
+}
+
+// enclosureLocation is the absolute path of the enclosure in dir if it was
+// downloaded, its url otherwise.
+func enclosureLocation(encl Enclosure, dir string) string {
+
+	if len(dir) == 0 {
+		return encl.String()
+	}
+
+	d, err := NewDownload(encl)
+	if err != nil {
+		return encl.String()
+	}
+
+	path, err := filepath.Abs(filepath.Join(dir, d.Filename))
+	if err != nil {
+		return encl.String()
+	}
+	if _, err := os.Stat(path); err != nil {
+		return encl.String()
+	}
+	return path
+}
+
+// writeM3U8 writes an extended M3U playlist.
+func writeM3U8(w io.Writer, entries []PlaylistEntry) error {
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, e := range entries {
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", e.Seconds, e.Title)
+		fmt.Fprintln(bw, e.Location)
+	}
+	return bw.Flush()
+}
+
+// writePLS writes a PLS playlist.
+func writePLS(w io.Writer, entries []PlaylistEntry) error {
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[playlist]")
+	for i, e := range entries {
+		fmt.Fprintf(bw, "File%d=%s\n", i+1, e.Location)
+		fmt.Fprintf(bw, "Title%d=%s\n", i+1, e.Title)
+		fmt.Fprintf(bw, "Length%d=%d\n", i+1, e.Seconds)
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(entries))
+	fmt.Fprintln(bw, "Version=2")
+	return bw.Flush()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// PodcastChannel holds the Podcasting 2.0 podcast:* elements of an RSS
+// channel.
+// https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+type PodcastChannel struct {
+	PodcastGuid    string           `xml:"https://podcastindex.org/namespace/1.0 guid,omitempty"`
+	PodcastLocked  PodcastLocked    `xml:"https://podcastindex.org/namespace/1.0 locked,omitempty"`
+	PodcastFunding []PodcastFunding `xml:"https://podcastindex.org/namespace/1.0 funding,omitempty"`
+	PodcastPersons []PodcastPerson  `xml:"https://podcastindex.org/namespace/1.0 person,omitempty"`
+}
+
+// PodcastItem holds the Podcasting 2.0 podcast:* elements of an RSS item.
+type PodcastItem struct {
+	PodcastChapters            *PodcastChapters            `xml:"https://podcastindex.org/namespace/1.0 chapters,omitempty"`
+	PodcastTranscripts         []PodcastTranscript         `xml:"https://podcastindex.org/namespace/1.0 transcript,omitempty"`
+	PodcastPersons             []PodcastPerson             `xml:"https://podcastindex.org/namespace/1.0 person,omitempty"`
+	PodcastSeason              *PodcastSeason              `xml:"https://podcastindex.org/namespace/1.0 season,omitempty"`
+	PodcastEpisode             *PodcastEpisode             `xml:"https://podcastindex.org/namespace/1.0 episode,omitempty"`
+	PodcastAlternateEnclosures []PodcastAlternateEnclosure `xml:"https://podcastindex.org/namespace/1.0 alternateEnclosure,omitempty"`
+	PodcastFunding             []PodcastFunding            `xml:"https://podcastindex.org/namespace/1.0 funding,omitempty"`
+}
+
+// PodcastLocked tells other platforms whether they may import the feed.
+type PodcastLocked struct {
+	Owner  string
+	Locked ITunesBool
+}
+
+func (l *PodcastLocked) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	var v struct {
+		Owner string `xml:"owner,attr"`
+		Value string `xml:",chardata"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	l.Owner = v.Owner
+	l.Locked = ITunesBool(strings.EqualFold(strings.TrimSpace(v.Value), "yes"))
+	return nil
+}
+
+// MarshalXML leaves out a feed that doesn't say whether it is locked.
+func (l PodcastLocked) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(l.Owner) == 0 && !l.Locked {
+		return nil
+	}
+	if len(l.Owner) > 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "owner"}, Value: l.Owner})
+	}
+	return e.EncodeElement(l.Locked.String(), start)
+}
+
+type PodcastFunding struct {
+	Url   string `xml:"url,attr"`
+	Label string `xml:",chardata"`
+}
+
+type PodcastChapters struct {
+	Url  string `xml:"url,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type PodcastTranscript struct {
+	Url      string `xml:"url,attr"`
+	Type     string `xml:"type,attr,omitempty"`
+	Language string `xml:"language,attr,omitempty"`
+	Rel      string `xml:"rel,attr,omitempty"`
+}
+
+type PodcastPerson struct {
+	Name  string `xml:",chardata"`
+	Role  string `xml:"role,attr,omitempty"`
+	Group string `xml:"group,attr,omitempty"`
+	Img   string `xml:"img,attr,omitempty"`
+	Href  string `xml:"href,attr,omitempty"`
+}
+
+type PodcastSeason struct {
+	Name   string
+	Number ITunesNumber
+}
+
+func (s *PodcastSeason) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	var v struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	s.Name = v.Name
+	s.Number = parseNumber(v.Value)
+	return nil
+}
+
+func (s PodcastSeason) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(s.Name) > 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "name"}, Value: s.Name})
+	}
+	return e.EncodeElement(int(s.Number), start)
+}
+
+type PodcastEpisode struct {
+	Display string `xml:"display,attr,omitempty"`
+	Number  string `xml:",chardata"`
+}
+
+type PodcastAlternateEnclosure struct {
+	Type    string          `xml:"type,attr,omitempty"`
+	Length  string          `xml:"length,attr,omitempty"`
+	Bitrate string          `xml:"bitrate,attr,omitempty"`
+	Height  string          `xml:"height,attr,omitempty"`
+	Lang    string          `xml:"lang,attr,omitempty"`
+	Title   string          `xml:"title,attr,omitempty"`
+	Rel     string          `xml:"rel,attr,omitempty"`
+	Codecs  string          `xml:"codecs,attr,omitempty"`
+	Default bool            `xml:"default,attr,omitempty"`
+	Sources []PodcastSource `xml:"https://podcastindex.org/namespace/1.0 source"`
+}
+
+type PodcastSource struct {
+	Uri         string `xml:"uri,attr"`
+	ContentType string `xml:"contentType,attr,omitempty"`
+}
+
+// Enclosures returns one Enclosure per source of the alternate enclosure,
+// so it can be handled like the regular enclosure of the item.
+func (a PodcastAlternateEnclosure) Enclosures() []Enclosure {
+
+	var enclosures []Enclosure
+	for _, source := range a.Sources {
+		enclosure := Enclosure{
+			Url:    source.Uri,
+			Length: a.Length,
+			Type:   a.Type,
+		}
+		if len(source.ContentType) > 0 {
+			enclosure.Type = source.ContentType
+		}
+		enclosures = append(enclosures, enclosure)
+	}
+	return enclosures
+}
+
+func (p PodcastPerson) String() string {
+
+	name := strings.TrimSpace(p.Name)
+	if len(p.Role) > 0 {
+		return name + " (" + p.Role + ")"
+	}
+	return name
+}
+
+// PodcastLines returns the "# Key: value" comment lines for the podcast
+// namespace fields that are set.
+func (pi PodcastItem) PodcastLines() []string {
+
+	var lines []string
+	if pi.PodcastChapters != nil {
+		lines = append(lines, "# Chapters: "+pi.PodcastChapters.Url)
+	}
+	for _, transcript := range pi.PodcastTranscripts {
+		lines = append(lines, "# Transcript: "+transcript.Url)
+	}
+	for _, person := range pi.PodcastPersons {
+		lines = append(lines, "# Person: "+person.String())
+	}
+	for _, funding := range pi.PodcastFunding {
+		lines = append(lines, "# Funding: "+funding.Url)
+	}
+	return lines
+}
+
+// PodcastLines returns the "# Key: value" comment lines for the podcast
+// namespace fields that are set.
+func (pc PodcastChannel) PodcastLines() []string {
+
+	var lines []string
+	if len(pc.PodcastGuid) > 0 {
+		lines = append(lines, "# PodcastGUID: "+strings.TrimSpace(pc.PodcastGuid))
+	}
+	if pc.PodcastLocked.Locked {
+		lines = append(lines, "# Locked: "+pc.PodcastLocked.Locked.String())
+	}
+	for _, person := range pc.PodcastPersons {
+		lines = append(lines, "# Person: "+person.String())
+	}
+	for _, funding := range pc.PodcastFunding {
+		lines = append(lines, "# Funding: "+funding.Url)
+	}
+	return lines
+}
@@ -3,27 +3,24 @@ package main
 import (
 	"bufio"
 	"bytes"
+	// imported as gocontext in every file, strip.go declares a context type
+	gocontext "context"
 	"crypto/sha1"
 	"encoding/xml"
-	"flag"
 	"fmt"
 	"go/doc"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"os/user"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-var numOfDays = flag.Int("days", 1, "Number of days back to download an episode")
-var outputFile = flag.String("output", ``, "Path of the output file")
-var new_feed_url = flag.String("add", ``, "Add feed url to the list of podcasts")
-
 const (
 	rssXmlns        = "http://www.itunes.com/dtds/podcast-1.0.dtd"
 	rssVersion      = "2.0"
@@ -42,17 +39,28 @@ type Rss2 struct {
 }
 
 type Channel struct {
-	Title         string `xml:"title"`
-	Link          string `xml:"link"`
-	Description   string `xml:"description"`
-	PubDate       string `xml:"pubDate"`
-	Items         []Item `xml:"item"`
-	LastBuildDate string `xml:"lastBuildDate"`
+	// ITunesTitle and AtomLinks must come before Title and Link, otherwise
+	// itunes:title and atom:link would also be decoded into them.
+	ITunesTitle   string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title,omitempty"`
+	AtomLinks     []AtomLink `xml:"http://www.w3.org/2005/Atom link,omitempty"`
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link,omitempty"`
+	Description   string     `xml:"description,omitempty"`
+	PubDate       string     `xml:"pubDate,omitempty"`
+	Items         []Item     `xml:"item,omitempty"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	ITunesChannel
+	PodcastChannel
 }
 
 func (c Channel) String() string {
 
-	desc := strings.TrimSpace(StripTags(c.Description))
+	desc := c.Description
+	if len(strings.TrimSpace(desc)) == 0 {
+		desc = c.ITunesSummary
+	}
+
+	desc = strings.TrimSpace(StripTags(desc))
 	if len(desc) > DESCRIPTION_LEN {
 		desc = desc[:DESCRIPTION_LEN] + " ..."
 	}
@@ -60,22 +68,40 @@ func (c Channel) String() string {
 	var buf bytes.Buffer
 	doc.ToText(&buf, strings.TrimSpace(desc), "# ", "", PARAGRAPH_WIDTH)
 
-	return fmt.Sprintf("##\n# %s\n# %s\n%s", c.Title, c.Link, buf.String())
+	header := []string{"##", "# " + c.Title, "# " + c.Link}
+	header = append(header, c.ITunesLines()...)
+	header = append(header, c.PodcastLines()...)
+	for k := range header {
+		header[k] = singleLine(header[k])
+	}
+	return fmt.Sprintf("%s\n%s", strings.Join(header, "\n"), buf.String())
 }
 
 type Item struct {
-	Title       string      `xml:"title"`
-	Link        string      `xml:"link"`
-	Guid        string      `xml:"guid"`
-	PubDate     string      `xml:"pubDate"`
-	Author      string      `xml:"author"`
-	Description string      `xml:"description"`
-	Enclosures  []Enclosure `xml:"enclosure"`
+	// ITunesTitle must come before Title, otherwise itunes:title would also
+	// be decoded into it.
+	ITunesTitle string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title,omitempty"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link,omitempty"`
+	Guid        Guid   `xml:"guid,omitempty"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	// ITunesItem must come before Author, otherwise itunes:author would
+	// also be decoded into the plain author field.
+	ITunesItem
+	Author      string      `xml:"author,omitempty"`
+	Description string      `xml:"description,omitempty"`
+	Enclosures  []Enclosure `xml:"enclosure,omitempty"`
+	PodcastItem
 }
 
 func (i Item) String() string {
 
-	desc := strings.TrimSpace(StripTags(i.Description))
+	desc := i.Description
+	if len(strings.TrimSpace(desc)) == 0 {
+		desc = i.ITunesSummary
+	}
+
+	desc = strings.TrimSpace(StripTags(desc))
 	if len(desc) > DESCRIPTION_LEN {
 		desc = desc[:DESCRIPTION_LEN] + " ..."
 	}
@@ -83,15 +109,34 @@ func (i Item) String() string {
 	var buf bytes.Buffer
 	doc.ToText(&buf, desc, "# ", "", PARAGRAPH_WIDTH)
 
-	desc = buf.String()
-	return fmt.Sprintf("# Title: %s\n# PubDate: %s\n# GUID: %s\n%s", strings.TrimSpace(i.Title),
-		i.PubDate, strings.TrimSpace(i.Guid), desc)
+	header := []string{
+		"# Title: " + strings.TrimSpace(i.Title),
+		"# PubDate: " + i.PubDate,
+		"# GUID: " + strings.TrimSpace(i.Guid.Value),
+	}
+	header = append(header, i.ITunesLines()...)
+	header = append(header, i.PodcastLines()...)
+	for k := range header {
+		header[k] = singleLine(header[k])
+	}
+	return fmt.Sprintf("%s\n%s", strings.Join(header, "\n"), buf.String())
+}
+
+// Guid is the guid of an item. Readers take it for the url of the episode
+// unless IsPermaLink is "false".
+type Guid struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr,omitempty"`
+}
+
+func (g Guid) String() string {
+	return g.Value
 }
 
 type Enclosure struct {
 	Url    string `xml:"url,attr"`
-	Length string `xml:"length,attr"`
-	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
 }
 
 func (e Enclosure) String() string {
@@ -103,6 +148,7 @@ func (e Enclosure) String() string {
 	return fmt.Sprintf("%s", encl)
 }
 
+// GetFileName returns the sanitized last path segment of the url.
 func GetFileName(uu string) (string, error) {
 
 	u, err := url.Parse(uu)
@@ -111,7 +157,11 @@ func GetFileName(uu string) (string, error) {
 	}
 
 	slice1 := strings.Split(u.Path, "/")
-	return slice1[len(slice1)-1], nil
+	filename := SanitizeFilename(slice1[len(slice1)-1])
+	if len(filename) == 0 {
+		return "", fmt.Errorf("no usable file name in %s", uu)
+	}
+	return filename, nil
 
 }
 
@@ -128,28 +178,78 @@ func StripUrl(uu string) (string, error) {
 
 func GetPodcastData(feed_url string) (Channel, error) {
 
-	res, err := http.Get(feed_url)
+	channel, _, err := FetchPodcastData(gocontext.Background(), feed_url, nil)
+	return channel, err
+}
+
+// newRequest is http.NewRequestWithContext with the user agent of the
+// config.
+func newRequest(ctx gocontext.Context, method string, url string) (*http.Request, error) {
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err == nil && len(config.UserAgent) > 0 {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
+	return req, err
+}
+
+// FetchPodcastData is GetPodcastData with a feed cache. When the feed is
+// cached, the request is sent with If-None-Match and If-Modified-Since and
+// the cached channel is returned on 304 Not Modified, with hit set to true.
+// The cache may be nil. The request is canceled when ctx is done.
+func FetchPodcastData(ctx gocontext.Context, feed_url string, cache *FeedCache) (channel Channel, hit bool, err error) {
+
+	req, err := newRequest(ctx, "GET", feed_url)
 	if err != nil {
-		return Channel{}, err
+		return Channel{}, false, err
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return Channel{}, err
+	var cached *CachedFeed
+	if cache != nil {
+		cached = cache.Load(feed_url)
+	}
+	if cached != nil {
+		if len(cached.ETag) > 0 {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if len(cached.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
 	}
 
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Channel{}, false, networkError(feed_url, err)
+	}
 	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.Channel, true, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return Channel{}, false, &HTTPStatusError{Url: feed_url, StatusCode: res.StatusCode, Status: res.Status}
+	}
+
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return Channel{}, err
+		return Channel{}, false, networkError(feed_url, err)
 	}
 
-	var feed Rss2
-	err = xml.Unmarshal(body, &feed)
+	channel, err = ParseFeed(body, res.Header.Get("Content-Type"))
 	if err != nil {
-		return Channel{}, err
+		return Channel{}, false, &ParseError{Url: feed_url, Err: err}
+	}
+
+	etag, lastModified := res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+	if cache != nil && (len(etag) > 0 || len(lastModified) > 0) {
+		err = cache.Store(CachedFeed{Url: feed_url, ETag: etag, LastModified: lastModified, Channel: channel})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		}
 	}
 
-	return feed.Channel, nil
+	return channel, false, nil
 }
 
 // readLines reads a whole file into memory
@@ -168,7 +268,8 @@ func readLines(path string) ([]string, error) {
 		curr_line := strings.Trim(scanner.Text(), "\t ")
 		match, _ := regexp.MatchString(HTTPS_REGEX, curr_line)
 		if match == true {
-			lines = append(lines, curr_line)
+			// the settings of the feed follow the url
+			lines = append(lines, strings.Fields(curr_line)[0])
 		}
 	}
 	return lines, scanner.Err()
@@ -228,18 +329,17 @@ func GenerateFeedListFile(path string) error {
 	return err1
 }
 
+// GetFeedList returns the urls of the feed list file of the config and its
+// path. The first run creates the file and the default config file.
 func GetFeedList() ([]string, string, error) {
 
-	usr, _ := user.Current()
-	//path, _ := os.Getwd()
-	//directories := strings.Split(path, "/")
-	feed_path := filepath.Join(usr.HomeDir, ".podcasts", "feeds.txt")
-	//fmt.Println(path)
-	//fmt.Println(feed_path)
+	feed_path := config.FeedsFile
 
 	if _, err := os.Stat(feed_path); os.IsNotExist(err) {
 		// http://stackoverflow.com/a/12518877
+		os.MkdirAll(filepath.Dir(feed_path), 0755)
 		GenerateFeedListFile(feed_path)
+		writeDefaultConfig(config.Path)
 	}
 
 	lines, err := readLines(feed_path)
@@ -248,67 +348,160 @@ func GetFeedList() ([]string, string, error) {
 
 }
 
-// https://github.com/jteeuwen/go-pkg-rss/blob/master/timeparser.go
-func ParseTime(formatted string) (time.Time, error) {
-	var layouts = [...]string{
-		"Mon, _2 Jan 2006 15:04:05 MST",
-		"Mon, _2 Jan 2006 15:04:05 -0700",
-		time.ANSIC,
-		time.UnixDate,
-		time.RubyDate,
-		time.RFC822,
-		time.RFC822Z,
-		time.RFC850,
-		time.RFC1123,
-		time.RFC1123Z,
-		time.RFC3339,
-		time.RFC3339Nano,
-		"Mon, 2, Jan 2006 15:4",
-		"02 Jan 2006 15:04:05 MST",
-	}
-	var t time.Time
-	var err error
-	formatted = strings.TrimSpace(formatted)
-	for _, layout := range layouts {
-		t, err = time.Parse(layout, formatted)
-		if !t.IsZero() {
-			break
+// DatedItem is an item with its parsed pubDate. When the pubDate can't be
+// parsed Err is why, and Date is the date of the feed if the item is the
+// first of the feed.
+type DatedItem struct {
+	Item     Item
+	Date     time.Time
+	Err      error
+	Fallback bool
+}
+
+// Date is the lastBuildDate of the channel, or its pubDate, zero if it has
+// neither.
+func (c Channel) Date() time.Time {
+
+	if t, err := ParseTime(c.LastBuildDate); err == nil {
+		return t
+	}
+	if t, err := ParseTime(c.PubDate); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// SortItems returns the items of the channel newest first. Items with the
+// same date keep the order of the feed and the items without a date come
+// last, also in the order of the feed. Only the first item, the newest in
+// the order of the feed, gets the date of the feed when it has none, so a
+// feed without dates doesn't bring its whole back catalog into a window.
+func SortItems(channel Channel) []DatedItem {
+
+	fallback := channel.Date()
+
+	dated := make([]DatedItem, len(channel.Items))
+	for i, item := range channel.Items {
+		dated[i].Item = item
+		dated[i].Date, dated[i].Err = ParseTime(item.PubDate)
+		if i == 0 && dated[i].Err != nil && !fallback.IsZero() {
+			dated[i].Date, dated[i].Fallback = fallback, true
 		}
 	}
-	return t, err
+
+	sort.SliceStable(dated, func(i, j int) bool {
+		if dated[i].Date.IsZero() || dated[j].Date.IsZero() {
+			return !dated[i].Date.IsZero() && dated[j].Date.IsZero()
+		}
+		return dated[i].Date.After(dated[j].Date)
+	})
+	return dated
 }
 
-func podcast_fetch(url string, dirname string, days int, ch chan<- string) {
+// FetchOptions selects the episodes of a feed that podcast_fetch writes to
+// the script and to the download queue.
+type FetchOptions struct {
+	Window Window
+	// Feeds are the settings of the feeds, by url.
+	Feeds map[string]FeedConfig
+	// All includes the episodes already seen in previous runs.
+	All   bool
+	State *StateDB
+	// Queue collects the downloads when not nil.
+	Queue *DownloadQueue
+	// Cache is used for conditional requests when not nil.
+	Cache *FeedCache
+	// Concurrency is the number of feeds fetched at the same time.
+	Concurrency int
+	// Timeout limits each feed request when not zero.
+	Timeout time.Duration
+}
+
+// podcast_fetch fetches a feed, selects its new episodes and writes their
+// part of the script to a .feed file in dirname.
+func podcast_fetch(ctx gocontext.Context, url string, dirname string, opts FetchOptions) FetchResult {
 
 	start := time.Now()
+	result := FetchResult{Url: url}
+
+	if opts.Timeout > 0 {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
 
 	now := time.Now().UTC()
-	channel, err := GetPodcastData(url)
+	channel, hit, err := FetchPodcastData(ctx, url, opts.Cache)
+
+	if opts.Cache != nil {
+		result.Cache = CacheMiss
+		if hit {
+			result.Cache = CacheHit
+		}
+	}
 
 	if err != nil {
-		ch <- fmt.Sprint(err) // send to channel ch
-		return
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
 	}
 
-	feed_array := []string{channel.String()}
-	for _, item := range channel.Items {
+	result.Channel = channel
+	result.Title = channel.Title
+	result.Items = len(channel.Items)
+
+	cfg := opts.Feeds[url]
+	result.Subdir = cfg.Dir
 
-		parsed, t1_err := ParseTime(item.PubDate)
-		if t1_err != nil {
+	// the smaller of -latest and the max setting of the feed
+	max := opts.Window.Latest
+	if cfg.Max > 0 && (max == 0 || cfg.Max < max) {
+		max = cfg.Max
+	}
+
+	// the window starts at the previous fetch, record this one
+	start_window := opts.Window.Start(url, opts.State)
+	opts.State.SetLastFetch(url, now)
+
+	feed_array := []string{channel.String()}
+	latest := 0
+	for _, d := range SortItems(channel) {
+
+		item, date := d.Item, d.Date
+		switch {
+		case d.Fallback:
+			fmt.Fprintf(os.Stderr, "podcasts: %s: %q: %v, using the date of the feed\n", url, strings.TrimSpace(item.Title), d.Err)
+		case d.Err != nil:
+			fmt.Fprintf(os.Stderr, "podcasts: %s: %q skipped: %v\n", url, strings.TrimSpace(item.Title), d.Err)
 			continue
 		}
 
-		if len(item.Enclosures) == 0 {
+		if len(item.Enclosures) == 0 || !cfg.Match(item) {
 			continue
 		}
 
-		parsed = parsed.UTC()
-		diff := now.Sub(parsed)
+		// not a break, the order of the feed doesn't matter
+		if !opts.Window.Contains(d.Date, start_window) {
+			continue
+		}
 
-		if diff.Hours() > float64(days)*24.0 {
+		// the newest episodes count even if they were seen before
+		latest++
+		if max > 0 && latest > max {
 			break
 		}
 
+		// only the enclosure of the preferred type, for every output
+		item.Enclosures = cfg.Enclosures(item)
+
+		// the downloads that failed or were stopped are queued again
+		pending := opts.Queue != nil && opts.State.IsPending(url, item)
+		if !opts.All && !pending && !opts.State.IsNew(url, item) {
+			continue
+		}
+		opts.State.MarkSeen(url, item)
+		result.Episodes = append(result.Episodes, item)
+
 		feed_array = append(feed_array, "#", item.String())
 		for _, encl := range item.Enclosures {
 
@@ -319,23 +512,33 @@ func podcast_fetch(url string, dirname string, days int, ch chan<- string) {
 			}
 			//fmt.Println("wget -O " + filename + " " + encl.String())
 			//fmt.Println("#")
-			feed_array = append(feed_array, "wget --no-clobber -O "+filename+" "+encl.String())
+			feed_array = append(feed_array, "wget --no-clobber -O "+ShellQuote(filename)+" "+ShellQuote(encl.String()))
+
+			if opts.Queue != nil {
+				if d, err := NewDownload(encl); err == nil {
+					d.Feed, d.Key, d.Subdir, d.Date = url, ItemKey(item), cfg.Dir, date
+					opts.Queue.Add(d)
+				}
+			}
 
 		}
 
 	}
 	feed_array = append(feed_array, "")
+	result.NewItems = len(result.Episodes)
 
 	h := sha1.New()
 	h.Write([]byte(url))
 	bs := h.Sum(nil)
+	result.Sha1 = fmt.Sprintf("%x", bs)
 	filename := fmt.Sprintf("%x.feed", bs)
 	filepath := filepath.Join(dirname, filename)
 
 	w, err := os.Create(filepath)
 	if err != nil {
-		ch <- fmt.Sprintf("couldn't create %s: %v\n", filepath, err)
-		return
+		result.Err = fmt.Errorf("couldn't create %s: %v", filepath, err)
+		result.Duration = time.Since(start)
+		return result
 	}
 
 	defer w.Close()
@@ -344,23 +547,14 @@ func podcast_fetch(url string, dirname string, days int, ch chan<- string) {
 	nbytes, err1 := w.WriteString(text)
 
 	if err1 != nil {
-		ch <- fmt.Sprintf("while reading %s: %v\n", url, err1)
-		return
-	}
-
-	secs := time.Since(start).Seconds()
-	channel_title := channel.Title
-	if len(channel_title) > 25 {
-		channel_title = channel_title[:25]
-	}
-
-	url_str := url
-	if len(url_str) > 50 {
-		url_str = url_str[:50]
+		result.Err = fmt.Errorf("while writing %s: %v", filepath, err1)
+		result.Duration = time.Since(start)
+		return result
 	}
 
-	ch <- fmt.Sprintf("%5.2fs : %-6d : %10x : %-25s : %s", secs, nbytes, bs[0:10], channel_title, url_str)
-
+	result.Bytes = nbytes
+	result.Duration = time.Since(start)
+	return result
 }
 
 func deleteFiles(path string, f os.FileInfo, err error) error {
@@ -423,66 +617,88 @@ func mergeDataOfFiles(folder string, extension string) string {
 
 }
 
-func main() {
-
-	flag.Parse()
+// fetchFeeds fetches the feeds into .feed files in feed_data_folder with
+// at most opts.Concurrency requests at a time and returns the results in
+// the order of feed_list. report, if not nil, is called with each result as
+// it finishes. When ctx is done the requests in flight are canceled and the
+// remaining feeds are skipped with the error of ctx, the .feed files of the
+// feeds that finished are kept. The error is set when feed_data_folder
+// can't be prepared, then nothing is fetched.
+func fetchFeeds(ctx gocontext.Context, feed_list []string, feed_data_folder string, opts FetchOptions, report func(FetchResult)) ([]FetchResult, error) {
 
-	// Try to add the new feed url to the podcast list
-	if len(*new_feed_url) > 0 {
-		match, _ := regexp.MatchString(HTTPS_REGEX, *new_feed_url)
-		if match == true {
-			addError := addUrl(*new_feed_url)
-			if addError != nil {
-				fmt.Fprintf(os.Stderr, "podcasts: %v\n", addError)
-			}
-		}
+	if err := os.MkdirAll(feed_data_folder, 0755); err != nil {
+		return nil, err
 	}
 
-	feed_list, feed_path, _ := GetFeedList()
-	feed_data_folder := filepath.Dir(feed_path)
-
 	// delete the .feed files if they exist
 	//feedExtensions := []string{".feed"}
 	err_walker := filepath.Walk(feed_data_folder, deleteFiles)
 	if err_walker != nil {
-		log.Fatal(err_walker)
-	}
+		return nil, err_walker
+	}
+
+	// report is called by one worker at a time
+	var mu sync.Mutex
+	results := make([]FetchResult, len(feed_list))
+	started := runPool(ctx, len(feed_list), opts.Concurrency, func(i int) {
+		results[i] = podcast_fetch(ctx, feed_list[i], feed_data_folder, opts)
+		if report != nil {
+			mu.Lock()
+			report(results[i])
+			mu.Unlock()
+		}
+	})
 
-	fmt.Printf("%s\n", constructPodcastHeader(WIDTH_HEADER))
+	for i := started; i < len(feed_list); i++ {
+		results[i] = FetchResult{Url: feed_list[i], Err: ctx.Err()}
+	}
 
-	// 1234567890
-	//       secs : nbytes :                 sha1 : Title                     : URL
+	return results, nil
+}
 
-	start := time.Now()
-	ch := make(chan string)
-	fmt.Printf("%6s : %6s : %20s : %-25s : %s\n", "secs", "nbytes", "sha1", "Title", "URL")
+func main() {
 
-	for _, url := range feed_list {
-		go podcast_fetch(url, feed_data_folder, *numOfDays, ch) // start a goroutine
+	var err error
+	config, err = LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "podcasts: %v\n", err)
+		os.Exit(2)
 	}
 
-	for range feed_list {
-		fmt.Println(<-ch)
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") && os.Args[1] != "-h" && os.Args[1] != "-help" {
+		// no command, keep the behavior of the older versions, -add adds a
+		// feed before the update
+		add, args := splitAddFlag(os.Args[1:])
+		if len(add) > 0 {
+			runCommand("add", []string{add})
+		}
+		os.Exit(runCommand("update", append([]string{"-script"}, args...)))
 	}
 
-	fmt.Printf("\n%5.2fs elapsed\n\n", time.Since(start).Seconds())
-
-	feed_text := mergeDataOfFiles(feed_data_folder, ".feed")
-
-	if len(*outputFile) == 0 {
-		fmt.Printf(feed_text)
-	} else {
-		writeText(feed_text, *outputFile)
-	}
+	os.Exit(runCommand(os.Args[1], os.Args[2:]))
+}
 
-	// delete the .feed files if they exist
-	//feedExtensions := []string{".feed"}
-	// clean up before finishing...
-	err_walker = filepath.Walk(feed_data_folder, deleteFiles)
-	if err_walker != nil {
-		log.Fatal(err_walker)
+// splitAddFlag takes the -add flag of the older versions, "-add=url" or
+// "-add url", out of the arguments.
+func splitAddFlag(args []string) (string, []string) {
+
+	var add string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimPrefix(strings.TrimPrefix(args[i], "-"), "-")
+		switch {
+		case args[i] == "--":
+			return add, append(rest, args[i:]...)
+		case strings.HasPrefix(name, "add="):
+			add = strings.TrimPrefix(name, "add=")
+		case name == "add" && i+1 < len(args):
+			add = args[i+1]
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
 	}
-
+	return add, rest
 }
 
 // http://siongui.github.io/2015/03/03/go-parse-web-feed-rss-atom/
@@ -0,0 +1,106 @@
+package main
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPodcastFetchHostile fetches a feed whose title, descriptions and
+// enclosure urls try to break out of the generated script.
+func TestPodcastFetchHostile(t *testing.T) {
+
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	state, err := OpenStateDB(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := podcast_fetch(gocontext.Background(), srv.URL+"/hostile.xml", dir, FetchOptions{State: state})
+	if result.Failed() {
+		t.Fatal(result.Err)
+	}
+	if result.NewItems != 2 {
+		t.Errorf("%d new episodes, want 2", result.NewItems)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, result.Sha1+".feed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wget []string
+	for _, line := range strings.Split(string(b), "\n") {
+		switch {
+		case len(line) == 0, strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "wget "):
+			wget = append(wget, line)
+		default:
+			t.Errorf("line outside of a comment: %q", line)
+		}
+	}
+
+	want := []string{
+		`wget --no-clobber -O a_b_c.mp3 'http://evil.example/$(touch pwned3)/a'\''b;c.mp3'`,
+		// no leading dash, wget would read the file name as options
+		`wget --no-clobber -O rf http://evil.example/ep/../../-rf`,
+	}
+	if strings.Join(wget, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wget lines\n%s\nwant\n%s", strings.Join(wget, "\n"), strings.Join(want, "\n"))
+	}
+
+	if !strings.Contains(string(b), "# Evil rm -rf ~ #\n") {
+		t.Errorf("the channel title is not a single comment line:\n%s", b)
+	}
+	if !strings.Contains(string(b), "# Title: Ep 1 $(touch pwned) touch pwned2; echo '\n") {
+		t.Errorf("the episode title is not a single comment line:\n%s", b)
+	}
+}
+
+// TestPodcastFetchRequeue checks that an episode whose download failed is
+// queued again on the next run, and not once it was downloaded.
+func TestPodcastFetchRequeue(t *testing.T) {
+
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	state, err := OpenStateDB(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := srv.URL + "/hostile.xml"
+
+	queued := func() int {
+		opts := FetchOptions{State: state, Queue: &DownloadQueue{}}
+		if result := podcast_fetch(gocontext.Background(), feed, dir, opts); result.Failed() {
+			t.Fatal(result.Err)
+		}
+		return len(opts.Queue.Downloads())
+	}
+
+	if n := queued(); n != 2 {
+		t.Fatalf("first run queued %d downloads, want 2", n)
+	}
+	// the downloads failed, nothing was marked
+	if n := queued(); n != 2 {
+		t.Errorf("second run queued %d downloads, want 2", n)
+	}
+
+	if err := state.Mark(feed, "evil-1", StatusDownloaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Mark(feed, "evil-2", StatusSkipped); err != nil {
+		t.Fatal(err)
+	}
+	if n := queued(); n != 0 {
+		t.Errorf("queued %d downloads after they were downloaded or skipped, want 0", n)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	gocontext "context"
+	"sync"
+)
+
+// runPool calls work with the indexes 0 to n-1, in order, on at most
+// workers goroutines at a time. It stops handing out indexes when ctx is
+// done, waits for the calls already started and returns their number: the
+// indexes from it on were not worked on.
+func runPool(ctx gocontext.Context, n int, workers int, work func(i int)) int {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+
+	started := 0
+loop:
+	for ; started < n; started++ {
+		// a done ctx wins over an idle worker
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- started:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return started
+}
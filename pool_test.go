@@ -0,0 +1,72 @@
+package main
+
+import (
+	gocontext "context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunPool(t *testing.T) {
+
+	var mu sync.Mutex
+	running, most := 0, 0
+	done := make([]bool, 20)
+
+	started := runPool(gocontext.Background(), len(done), 3, func(i int) {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+		done[i] = true
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if started != len(done) {
+		t.Errorf("%d started, want %d", started, len(done))
+	}
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("%d not worked on", i)
+		}
+	}
+	if most > 3 {
+		t.Errorf("%d workers at a time, want at most 3", most)
+	}
+}
+
+// TestRunPoolCanceled checks that no index is handed out after ctx is done.
+func TestRunPoolCanceled(t *testing.T) {
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	var mu sync.Mutex
+	var worked []int
+
+	started := runPool(ctx, 10, 2, func(i int) {
+		mu.Lock()
+		worked = append(worked, i)
+		mu.Unlock()
+		if i == 3 {
+			cancel()
+		}
+	})
+
+	if started < 4 || started > 6 {
+		t.Errorf("%d started, want 4 to 6", started)
+	}
+	if len(worked) != started {
+		t.Errorf("%d worked on, %d started", len(worked), started)
+	}
+	for _, i := range worked {
+		if i >= started {
+			t.Errorf("%d worked on, not below %d", i, started)
+		}
+	}
+}
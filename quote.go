@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ShellQuote quotes s so that a POSIX shell reads it as a single word
+// without expanding anything in it.
+func ShellQuote(s string) string {
+
+	if len(s) == 0 {
+		return "''"
+	}
+
+	safe := true
+	for _, r := range s {
+		if !isShellSafe(r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+
+	// inside single quotes everything is literal except the single quote
+	// itself, which has to be closed, escaped and reopened: ' -> '\''
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func isShellSafe(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("%+,-./:=@_", r))
+}
+
+// SanitizeFilename turns the last path segment of an enclosure url into a
+// file name that is safe to create: no path separators, no control or
+// shell characters, and no leading dots or dashes. It returns an empty
+// string when nothing usable is left.
+func SanitizeFilename(name string) string {
+
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			return r
+		case strings.ContainsRune(".-_+", r):
+			return r
+		}
+		return '_'
+	}, name)
+
+	cleaned = strings.TrimLeft(cleaned, ".-")
+	if len(strings.Trim(cleaned, "_")) == 0 {
+		return ""
+	}
+	return cleaned
+}
+
+// singleLine replaces line breaks with spaces, so a value from the feed
+// can't end a "#" comment line of the generated script.
+func singleLine(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\n', '\r', '\v', '\f', '\u0085', '\u2028', '\u2029':
+			return ' '
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"episode-1.mp3", "episode-1.mp3"},
+		{"http://example.com/a/b.mp3", "http://example.com/a/b.mp3"},
+		{"it's", `'it'\''s'`},
+		{"'", `''\'''`},
+		{"$(rm -rf ~)", "'$(rm -rf ~)'"},
+		{"`id`", "'`id`'"},
+		{"a;reboot", "'a;reboot'"},
+		{"a b", "'a b'"},
+		{"a\nb", "'a\nb'"},
+	}
+
+	for _, tt := range tests {
+		if got := ShellQuote(tt.in); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestShellQuoteShell checks that a POSIX shell reads the quoted strings
+// back unchanged, without running anything.
+func TestShellQuoteShell(t *testing.T) {
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+
+	for _, in := range []string{"", "'", "''", "$(touch pwned)", "`touch pwned`", "a;touch pwned", "a\nb", `\'"`, "$HOME"} {
+		out, err := exec.Command(sh, "-c", "printf %s "+ShellQuote(in)).Output()
+		if err != nil {
+			t.Fatalf("sh -c printf %s: %v", ShellQuote(in), err)
+		}
+		if string(out) != in {
+			t.Errorf("sh read %q back as %q", in, out)
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"episode-1.mp3", "episode-1.mp3"},
+		{"..", ""},
+		{"../../etc/passwd", "_.._etc_passwd"},
+		{"-rf.mp3", "rf.mp3"},
+		{"--output=x.mp3", "output_x.mp3"},
+		{".hidden.mp3", "hidden.mp3"},
+		{"a b$(id).mp3", "a_b__id_.mp3"},
+		{"a\nb.mp3", "a_b.mp3"},
+		{"", ""},
+		{"$$$", ""},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGetFileName(t *testing.T) {
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://example.com/a/episode.mp3", "episode.mp3"},
+		{"http://example.com/a/episode.mp3?x=1#y", "episode.mp3"},
+		{"http://example.com/a/..", ""},
+		{"http://example.com/a/-rf.mp3", "rf.mp3"},
+		// the path is decoded before it is split
+		{"http://example.com/a/..%2F..%2Fetc%2Fpasswd", "passwd"},
+		{"http://example.com/a/b%2F-x.mp3", "x.mp3"},
+		{"http://example.com/a/ep%0a1.mp3", "ep_1.mp3"},
+		{"http://example.com/a/%0a", ""},
+		{"http://example.com/a/", ""},
+		{"http://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := GetFileName(tt.url)
+		if len(tt.want) == 0 {
+			if err == nil {
+				t.Errorf("GetFileName(%q) = %q, want an error", tt.url, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("GetFileName(%q) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"title", "title"},
+		{"a\nrm -rf ~", "a rm -rf ~"},
+		{"a\r\nb", "a  b"},
+		{"a\vb\fc", "a b c"},
+		{"a\u0085b\u2028c\u2029d", "a b c d"},
+	}
+
+	for _, tt := range tests {
+		if got := singleLine(tt.in); got != tt.want {
+			t.Errorf("singleLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
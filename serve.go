@@ -0,0 +1,175 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mirror serves the episodes downloaded into a folder, with a feed per
+// podcast and a merged feed whose enclosures point at the local copies.
+type Mirror struct {
+	Dir     string
+	Title   string
+	Feeds   []string
+	Configs map[string]FeedConfig
+	Cache   *FeedCache
+	Timeout time.Duration
+
+	mu      sync.RWMutex
+	results []FetchResult
+	updated time.Time
+}
+
+// Refresh fetches the feeds and keeps the episodes that have a local copy.
+// A feed that fails keeps the episodes of the previous refresh.
+func (m *Mirror) Refresh(ctx gocontext.Context) {
+
+	m.mu.RLock()
+	previous := m.results
+	m.mu.RUnlock()
+
+	results := make([]FetchResult, len(m.Feeds))
+	for i, feed_url := range m.Feeds {
+		if m.Configs[feed_url].Disabled {
+			results[i] = FetchResult{Url: feed_url, Err: errFeedDisabled}
+			continue
+		}
+		results[i] = m.fetch(ctx, feed_url)
+		if results[i].Failed() {
+			fmt.Fprintf(os.Stderr, "podcasts: %v\n", results[i].Err)
+			if i < len(previous) && previous[i].Url == feed_url && !previous[i].Failed() {
+				results[i] = previous[i]
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.results = results
+	m.updated = time.Now()
+	m.mu.Unlock()
+}
+
+func (m *Mirror) fetch(ctx gocontext.Context, feed_url string) FetchResult {
+
+	if m.Timeout > 0 {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	channel, _, err := FetchPodcastData(ctx, feed_url, m.Cache)
+	result := FetchResult{Url: feed_url, Err: err, Duration: time.Since(start)}
+	if err != nil {
+		return result
+	}
+
+	cfg := m.Configs[feed_url]
+	dir := feedDir(m.Dir, cfg.Dir)
+
+	result.Channel = channel
+	result.Title = channel.Title
+	result.Items = len(channel.Items)
+	result.Subdir = cfg.Dir
+	for _, item := range channel.Items {
+		if !cfg.Match(item) {
+			continue
+		}
+		item.Enclosures = cfg.Enclosures(item)
+		for _, encl := range item.Enclosures {
+			if enclosureLocation(encl, dir) != encl.String() {
+				result.Episodes = append(result.Episodes, item)
+				break
+			}
+		}
+	}
+	result.NewItems = len(result.Episodes)
+	return result
+}
+
+// feedOptions points the enclosures at the files of the mirror, under the
+// host the request was sent to.
+func (m *Mirror) feedOptions(r *http.Request) FeedOptions {
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host
+	return FeedOptions{Title: m.Title, Link: base + "/", Dir: m.Dir, BaseUrl: base + "/files"}
+}
+
+func (m *Mirror) Handler() http.Handler {
+
+	mux := http.NewServeMux()
+	// http.FileServer answers Range requests
+	mux.Handle("/files/", http.StripPrefix("/files/", http.FileServer(http.Dir(m.Dir))))
+	mux.HandleFunc("/feed.xml", m.serveMerged)
+	mux.HandleFunc("/feeds/", m.servePodcast)
+	mux.HandleFunc("/", m.serveIndex)
+	return mux
+}
+
+func (m *Mirror) serveMerged(w http.ResponseWriter, r *http.Request) {
+
+	m.mu.RLock()
+	results := m.results
+	m.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	writeMergedFeed(w, results, m.feedOptions(r))
+}
+
+// servePodcast serves /feeds/<n>.xml, n being the number of the feed in
+// the feed list.
+func (m *Mirror) servePodcast(w http.ResponseWriter, r *http.Request) {
+
+	name := strings.TrimPrefix(r.URL.Path, "/feeds/")
+	n, err := strconv.Atoi(strings.TrimSuffix(name, ".xml"))
+	if err != nil || !strings.HasSuffix(name, ".xml") {
+		http.NotFound(w, r)
+		return
+	}
+
+	m.mu.RLock()
+	results := m.results
+	m.mu.RUnlock()
+
+	if n < 1 || n > len(results) || results[n-1].Failed() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	writeRss(w, podcastFeed(results[n-1], m.feedOptions(r)))
+}
+
+func (m *Mirror) serveIndex(w http.ResponseWriter, r *http.Request) {
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	m.mu.RLock()
+	results := m.results
+	updated := m.updated
+	m.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<title>%s</title>\n<h1>%s</h1>\n", htmlEscaper(m.Title), htmlEscaper(m.Title))
+	fmt.Fprintf(w, "<p><a href=\"/feed.xml\">All podcasts</a> &middot; <a href=\"/files/\">Files</a> &middot; updated %s</p>\n<ul>\n", updated.Format("2006-01-02 15:04"))
+	for i, res := range results {
+		if res.Failed() {
+			continue
+		}
+		fmt.Fprintf(w, "<li><a href=\"/feeds/%d.xml\">%s</a> (%d episodes)</li>\n", i+1, htmlEscaper(res.Title), len(res.Episodes))
+	}
+	fmt.Fprintf(w, "</ul>\n")
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StatusSeen       = "seen"
+	StatusDownloaded = "downloaded"
+	StatusPlayed     = "played"
+	StatusSkipped    = "skipped"
+)
+
+// EpisodeState is what is known about an episode from previous runs. An
+// episode that has a state has been seen.
+type EpisodeState struct {
+	Title      string    `json:"title,omitempty"`
+	FirstSeen  time.Time `json:"first_seen"`
+	Downloaded bool      `json:"downloaded,omitempty"`
+	Played     bool      `json:"played,omitempty"`
+	Skipped    bool      `json:"skipped,omitempty"`
+	// File is the downloaded file in the download folder, empty if it was
+	// downloaded before it was recorded or was removed since.
+	File      string    `json:"file,omitempty"`
+	Published time.Time `json:"published,omitzero"`
+}
+
+func (e EpisodeState) Status() string {
+	switch {
+	case e.Played:
+		return StatusPlayed
+	case e.Skipped:
+		return StatusSkipped
+	case e.Downloaded:
+		return StatusDownloaded
+	}
+	return StatusSeen
+}
+
+// StateDB is the episode state database, a JSON file keyed by feed url
+// and episode guid. It is safe for concurrent use.
+type StateDB struct {
+	mu    sync.Mutex
+	path  string
+	Feeds map[string]map[string]*EpisodeState `json:"feeds"`
+	// Fetched is the time of the last successful fetch of each feed.
+	Fetched map[string]time.Time `json:"fetched,omitempty"`
+}
+
+// OpenStateDB loads the database from path. A missing file is an empty
+// database.
+func OpenStateDB(path string) (*StateDB, error) {
+
+	db := &StateDB{path: path, Feeds: make(map[string]map[string]*EpisodeState)}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, db); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if db.Feeds == nil {
+		db.Feeds = make(map[string]map[string]*EpisodeState)
+	}
+	return db, nil
+}
+
+// Save writes the database to a temporary file and renames it over the
+// previous one, so a crash never leaves a truncated database.
+func (db *StateDB) Save() error {
+
+	db.mu.Lock()
+	b, err := json.MarshalIndent(db, "", "  ")
+	db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(db.path), filepath.Base(db.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), db.path)
+}
+
+// ItemKey returns the key of an item in the database: its guid, or the
+// first enclosure url or the title for feeds without guids.
+func ItemKey(item Item) string {
+
+	if guid := strings.TrimSpace(item.Guid.Value); len(guid) > 0 {
+		return guid
+	}
+	if len(item.Enclosures) > 0 {
+		return item.Enclosures[0].String()
+	}
+	return strings.TrimSpace(item.Title)
+}
+
+// Get returns the state of an episode, or nil if it was never seen.
+func (db *StateDB) Get(feed string, key string) *EpisodeState {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if state, ok := db.Feeds[feed][key]; ok {
+		copied := *state
+		return &copied
+	}
+	return nil
+}
+
+// IsNew reports whether the item was never seen before.
+func (db *StateDB) IsNew(feed string, item Item) bool {
+	return db.Get(feed, ItemKey(item)) == nil
+}
+
+// IsPending reports whether the item was seen but not downloaded, played or
+// skipped: its download failed, was stopped or never started.
+func (db *StateDB) IsPending(feed string, item Item) bool {
+	state := db.Get(feed, ItemKey(item))
+	return state != nil && state.Status() == StatusSeen
+}
+
+// state returns the state of an episode, creating it if needed. The
+// caller must hold db.mu.
+func (db *StateDB) state(feed string, key string) *EpisodeState {
+
+	episodes, ok := db.Feeds[feed]
+	if !ok {
+		episodes = make(map[string]*EpisodeState)
+		db.Feeds[feed] = episodes
+	}
+
+	state, ok := episodes[key]
+	if !ok {
+		state = &EpisodeState{FirstSeen: time.Now().UTC()}
+		episodes[key] = state
+	}
+	return state
+}
+
+// LastFetch returns the time of the last successful fetch of the feed, zero
+// if it was never fetched.
+func (db *StateDB) LastFetch(feed string) time.Time {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.Fetched[feed]
+}
+
+// SetLastFetch records a successful fetch of the feed.
+func (db *StateDB) SetLastFetch(feed string, t time.Time) {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.Fetched == nil {
+		db.Fetched = make(map[string]time.Time)
+	}
+	db.Fetched[feed] = t
+}
+
+// MarkSeen records the item as seen.
+func (db *StateDB) MarkSeen(feed string, item Item) {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	state := db.state(feed, ItemKey(item))
+	state.Title = strings.TrimSpace(item.Title)
+}
+
+// Mark sets the status of an episode of a feed. Marking an episode as
+// seen only records it.
+func (db *StateDB) Mark(feed string, key string, status string) error {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	state := db.state(feed, key)
+	switch status {
+	case StatusSeen:
+	case StatusDownloaded:
+		state.Downloaded = true
+	case StatusPlayed:
+		state.Played = true
+	case StatusSkipped:
+		state.Skipped = true
+	default:
+		return fmt.Errorf("unknown episode status: %q", status)
+	}
+	return nil
+}
+
+// MarkDownloaded records the download of an episode published at date into
+// file, relative to the download folder.
+func (db *StateDB) MarkDownloaded(feed string, key string, file string, date time.Time) {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	state := db.state(feed, key)
+	state.Downloaded = true
+	state.File = file
+	state.Published = date
+}
+
+// DownloadedFiles returns the keys of the episodes of the feed that have a
+// downloaded file, the newest first.
+func (db *StateDB) DownloadedFiles(feed string) []string {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var keys []string
+	for key, state := range db.Feeds[feed] {
+		if len(state.File) > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	episodes := db.Feeds[feed]
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := episodes[keys[i]], episodes[keys[j]]
+		if !a.Published.Equal(b.Published) {
+			return a.Published.After(b.Published)
+		}
+		if !a.FirstSeen.Equal(b.FirstSeen) {
+			return a.FirstSeen.After(b.FirstSeen)
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// RemoveFile records that the file of the episode was removed. The episode
+// stays downloaded, so it isn't downloaded again.
+func (db *StateDB) RemoveFile(feed string, key string) {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if state, ok := db.Feeds[feed][key]; ok {
+		state.File = ""
+	}
+}
+
+// MarkAll sets the status of every known episode with the given key, in
+// any feed, and returns the number of episodes found.
+func (db *StateDB) MarkAll(key string, status string) (int, error) {
+
+	db.mu.Lock()
+	var feeds []string
+	for feed, episodes := range db.Feeds {
+		if _, ok := episodes[key]; ok {
+			feeds = append(feeds, feed)
+		}
+	}
+	db.mu.Unlock()
+
+	for _, feed := range feeds {
+		if err := db.Mark(feed, key, status); err != nil {
+			return 0, err
+		}
+	}
+	return len(feeds), nil
+}
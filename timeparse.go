@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// monthNames maps the English, German, French and Spanish month names and
+// abbreviations, in lower case, to the abbreviations time.Parse knows.
+var monthNames = map[string]string{
+	"jan": "Jan", "january": "Jan", "januar": "Jan", "jän": "Jan", "janv": "Jan", "janvier": "Jan", "ene": "Jan", "enero": "Jan",
+	"feb": "Feb", "february": "Feb", "februar": "Feb", "févr": "Feb", "fév": "Feb", "fevr": "Feb", "février": "Feb", "fevrier": "Feb", "febrero": "Feb",
+	"mar": "Mar", "march": "Mar", "mär": "Mar", "märz": "Mar", "mrz": "Mar", "mars": "Mar", "marzo": "Mar",
+	"apr": "Apr", "april": "Apr", "avr": "Apr", "avril": "Apr", "abr": "Apr", "abril": "Apr",
+	"may": "May", "mai": "May", "mayo": "May",
+	"jun": "Jun", "june": "Jun", "juni": "Jun", "juin": "Jun", "junio": "Jun",
+	"jul": "Jul", "july": "Jul", "juli": "Jul", "juil": "Jul", "juillet": "Jul", "julio": "Jul",
+	"aug": "Aug", "august": "Aug", "août": "Aug", "aout": "Aug", "ago": "Aug", "agosto": "Aug",
+	"sep": "Sep", "sept": "Sep", "september": "Sep", "septembre": "Sep", "septiembre": "Sep", "set": "Sep", "setiembre": "Sep",
+	"oct": "Oct", "october": "Oct", "okt": "Oct", "oktober": "Oct", "octobre": "Oct", "octubre": "Oct",
+	"nov": "Nov", "november": "Nov", "novembre": "Nov", "noviembre": "Nov",
+	"dec": "Dec", "december": "Dec", "dez": "Dec", "dezember": "Dec", "déc": "Dec", "décembre": "Dec", "decembre": "Dec", "dic": "Dec", "diciembre": "Dec",
+}
+
+// zoneOffsets are the offsets in hours of the zone abbreviations found in
+// feeds. Other abbreviations, such as "SGT", are read as UTC like time.Parse
+// does.
+var zoneOffsets = map[string]float64{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"BST": 1, "WEST": 1, "CET": 1, "MEZ": 1,
+	"CEST": 2, "MESZ": 2, "EET": 2,
+	"EEST": 3, "MSK": 3,
+	"IST":  5.5,
+	"JST":  9,
+	"AEST": 10, "AEDT": 11,
+	"NZST": 12, "NZDT": 13,
+	"AST": -4, "ADT": -3,
+	"EST": -5, "EDT": -4,
+	"CST": -6, "CDT": -5,
+	"MST": -7, "MDT": -6,
+	"PST": -8, "PDT": -7,
+	"AKST": -9, "AKDT": -8,
+	"HST": -10,
+}
+
+// dateFillers are the words between the parts of a date.
+var dateFillers = map[string]bool{"de": true, "del": true, "um": true, "à": true, "a": true, "las": true, "at": true}
+
+// timeLayouts are tried in order on the normalized date, which has no
+// weekday, no commas, English month abbreviations and numeric zones.
+var timeLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+	"2 Jan 2006 15:4",
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses the date of a feed or an item. It is tolerant of the
+// dates real feeds have: RFC 822 with or without weekday, seconds or a
+// zone, German, French and Spanish month names, named zones such as "PST"
+// and "MESZ", single digit days and hours, RFC 3339 and extra whitespace.
+// Dates without a zone or with an unknown one are UTC.
+// Based on https://github.com/jteeuwen/go-pkg-rss/blob/master/timeparser.go
+func ParseTime(formatted string) (time.Time, error) {
+
+	normalized := normalizeTime(formatted)
+	if len(normalized) == 0 {
+		return time.Time{}, fmt.Errorf("no date")
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format %q", strings.TrimSpace(formatted))
+}
+
+// normalizeTime rewrites a date into the forms of timeLayouts.
+func normalizeTime(s string) string {
+
+	fields := strings.Fields(strings.Replace(s, ",", " ", -1))
+
+	months := 0
+	for _, f := range fields {
+		if _, ok := monthNames[strings.ToLower(strings.TrimSuffix(f, "."))]; ok {
+			months++
+		}
+	}
+
+	var out []string
+	for i, f := range fields {
+
+		// "2." in German dates, "janv." in French ones
+		word := strings.TrimSuffix(f, ".")
+		lower := strings.ToLower(word)
+		month, isMonth := monthNames[lower]
+
+		// the weekday is redundant, whatever the language, the Spanish
+		// "mar" is Tuesday when a month follows
+		if i == 0 && len(fields) > 1 && isLetters(word) && (!isMonth || months > 1) {
+			continue
+		}
+
+		if isMonth {
+			out = append(out, month)
+			continue
+		}
+
+		// "de" in "2 de enero de 2026", "um" in "2. Jan 2026 um 10:00"
+		if isLetters(word) && dateFillers[lower] {
+			continue
+		}
+
+		if offset, ok := zoneOffsets[strings.ToUpper(word)]; ok && i > 0 {
+			out = append(out, formatOffset(offset))
+			continue
+		}
+
+		// "SGT", after the month names
+		if i > 1 && isZoneName(word) {
+			out = append(out, "+0000")
+			continue
+		}
+
+		// "+01:00" and "GMT+0100" as -0700
+		zone := strings.TrimPrefix(strings.TrimPrefix(word, "GMT"), "UTC")
+		if i > 0 && len(zone) == 6 && (zone[0] == '+' || zone[0] == '-') && zone[3] == ':' {
+			zone = zone[:3] + zone[4:]
+		}
+		if i > 0 && len(zone) == 5 && (zone[0] == '+' || zone[0] == '-') && isDigits(zone[1:]) {
+			out = append(out, zone)
+			continue
+		}
+
+		out = append(out, word)
+	}
+	return strings.Join(out, " ")
+}
+
+func formatOffset(hours float64) string {
+
+	sign := '+'
+	if hours < 0 {
+		sign, hours = '-', -hours
+	}
+	minutes := int(hours*60 + 0.5)
+	return fmt.Sprintf("%c%02d%02d", sign, minutes/60, minutes%60)
+}
+
+// isZoneName reports whether s looks like a zone abbreviation, two to five
+// upper case letters.
+func isZoneName(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return len(s) >= 2 && len(s) <= 5
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
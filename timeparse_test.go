@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+
+	utc := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Fri, 16 Oct 2026 10:00:00 +0000", utc("2026-10-16T10:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 GMT", utc("2026-10-16T10:00:00Z")},
+		{"16 Oct 2026 10:00:00 +0200", utc("2026-10-16T08:00:00Z")},
+		{"Fri, 16 Oct 26 10:00:00 +0000", utc("2026-10-16T10:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 +02:00", utc("2026-10-16T08:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 GMT+0100", utc("2026-10-16T09:00:00Z")},
+		{"2026-10-16T10:00:00+02:00", utc("2026-10-16T08:00:00Z")},
+		{"2026-10-16T10:00:00.5Z", utc("2026-10-16T10:00:00.5Z")},
+		{"2026-10-16 10:00:00", utc("2026-10-16T10:00:00Z")},
+		{"2026-10-16", utc("2026-10-16T00:00:00Z")},
+		{"Fri Oct 16 10:00:00 -0700 2026", utc("2026-10-16T17:00:00Z")},
+
+		// missing seconds, single digit days and hours
+		{"Fri, 16 Oct 2026 10:00 +0000", utc("2026-10-16T10:00:00Z")},
+		{"Fri, 6 Oct 2026 9:05:00 +0000", utc("2026-10-06T09:05:00Z")},
+		{"Mon, 2, Jan 2026 9:5", utc("2026-01-02T09:05:00Z")},
+		{"Fri, 16 Oct 2026", utc("2026-10-16T00:00:00Z")},
+
+		// named zones
+		{"Fri, 16 Oct 2026 10:00:00 PST", utc("2026-10-16T18:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 EDT", utc("2026-10-16T14:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 MESZ", utc("2026-10-16T08:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 IST", utc("2026-10-16T04:30:00Z")},
+
+		// unknown zones are UTC, as time.Parse reads them
+		{"Fri, 16 Oct 2026 10:00:00 SGT", utc("2026-10-16T10:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 HKT", utc("2026-10-16T10:00:00Z")},
+		{"Fri, 16 Oct 2026 10:00:00 KST", utc("2026-10-16T10:00:00Z")},
+		{"Fri Oct 16 10:00:00 SGT 2026", utc("2026-10-16T10:00:00Z")},
+
+		// German, French and Spanish
+		{"Fr, 16. Okt 2026 10:00:00 +0200", utc("2026-10-16T08:00:00Z")},
+		{"Freitag, 16. Oktober 2026 um 10:00 MESZ", utc("2026-10-16T08:00:00Z")},
+		{"16. März 2026 10:00", utc("2026-03-16T10:00:00Z")},
+		{"ven., 16 oct. 2026 10:00:00 +0200", utc("2026-10-16T08:00:00Z")},
+		{"16 févr. 2026 10:00", utc("2026-02-16T10:00:00Z")},
+		{"vendredi 16 décembre 2026 à 10:00", utc("2026-12-16T10:00:00Z")},
+		{"vie, 16 oct 2026 10:00:00 +0200", utc("2026-10-16T08:00:00Z")},
+		{"16 de enero de 2026 a las 10:00", utc("2026-01-16T10:00:00Z")},
+		// the Spanish Tuesday is also a month abbreviation
+		{"mar, 13 ene 2026 10:00:00 +0000", utc("2026-01-13T10:00:00Z")},
+
+		// extra whitespace
+		{"  Fri,  16   Oct 2026\t10:00:00  +0000 \n", utc("2026-10-16T10:00:00Z")},
+		{"Fri,16 Oct 2026 10:00:00 +0000", utc("2026-10-16T10:00:00Z")},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "  ", "yesterday", "16 Foo 2026", "Fri, 32 Oct 2026 10:00:00 +0000"} {
+		if got, err := ParseTime(in); err == nil {
+			t.Errorf("ParseTime(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+// TestSortItemsFallback checks that only the first item of the feed gets
+// the date of the feed when its own can't be read.
+func TestSortItemsFallback(t *testing.T) {
+
+	channel := Channel{
+		LastBuildDate: "Sun, 18 Oct 2026 10:00:00 +0000",
+		Items: []Item{
+			{Title: "new", PubDate: "someday"},
+			{Title: "dated", PubDate: "Fri, 16 Oct 2026 10:00:00 +0000"},
+			{Title: "old", PubDate: "long ago"},
+			{Title: "older"},
+		},
+	}
+
+	var titles []string
+	for _, d := range SortItems(channel) {
+		titles = append(titles, d.Item.Title)
+		if want := d.Item.Title == "new"; d.Fallback != want {
+			t.Errorf("%q: fallback %v, want %v", d.Item.Title, d.Fallback, want)
+		}
+		if want := d.Item.Title == "new" || d.Item.Title == "dated"; d.Date.IsZero() == want {
+			t.Errorf("%q: date %v", d.Item.Title, d.Date)
+		}
+	}
+	if got, want := fmt.Sprint(titles), "[new dated old older]"; got != want {
+		t.Errorf("order %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tomlTable is a table of a TOML file, the top level one has no name.
+type tomlTable struct {
+	Name   []string
+	Line   int
+	Values map[string]tomlValue
+}
+
+// tomlValue is a value of a TOML file as text: a string without its quotes,
+// or an integer, float or boolean as written.
+type tomlValue struct {
+	Text string
+	Line int
+}
+
+// parseToml parses the subset of TOML the config file needs: comments,
+// tables with bare, quoted and dotted names, and keys with string, integer,
+// float and boolean values. Arrays, inline tables and multiline strings are
+// not supported.
+func parseToml(text string) ([]tomlTable, error) {
+
+	tables := []tomlTable{{Values: make(map[string]tomlValue)}}
+	seen := map[string]bool{}
+
+	for n, line := range strings.Split(text, "\n") {
+
+		n++
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("%d: arrays of tables are not supported", n)
+			}
+			name, rest, err := parseTomlKey(line[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%d: %v", n, err)
+			}
+			if !strings.HasPrefix(rest, "]") || !isTomlEnd(rest[1:]) {
+				return nil, fmt.Errorf("%d: invalid table header", n)
+			}
+			id := strings.Join(name, "\x00")
+			if seen[id] {
+				return nil, fmt.Errorf("%d: table [%s] defined twice", n, strings.Join(name, "."))
+			}
+			seen[id] = true
+			tables = append(tables, tomlTable{Name: name, Line: n, Values: make(map[string]tomlValue)})
+			continue
+		}
+
+		key, rest, err := parseTomlKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %v", n, err)
+		}
+		if len(key) != 1 {
+			return nil, fmt.Errorf("%d: dotted keys are not supported", n)
+		}
+		if !strings.HasPrefix(rest, "=") {
+			return nil, fmt.Errorf("%d: missing = after %q", n, key[0])
+		}
+		value, err := parseTomlValue(strings.TrimSpace(rest[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %v", n, key[0], err)
+		}
+
+		values := tables[len(tables)-1].Values
+		if _, ok := values[key[0]]; ok {
+			return nil, fmt.Errorf("%d: %s set twice", n, key[0])
+		}
+		values[key[0]] = tomlValue{Text: value, Line: n}
+	}
+	return tables, nil
+}
+
+// parseTomlKey parses a dotted key at the start of s and returns its parts
+// and the rest of s after the whitespace that follows it.
+func parseTomlKey(s string) ([]string, string, error) {
+
+	var parts []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+			part, rest, err := parseTomlString(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts, s = append(parts, part), rest
+		default:
+			i := 0
+			for i < len(s) && isTomlBareKey(s[i]) {
+				i++
+			}
+			if i == 0 {
+				return nil, "", fmt.Errorf("missing key")
+			}
+			parts, s = append(parts, s[:i]), s[i:]
+		}
+
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, ".") {
+			return parts, s, nil
+		}
+		s = s[1:]
+	}
+}
+
+// parseTomlValue parses the value of a key, followed by nothing but a
+// comment.
+func parseTomlValue(s string) (string, error) {
+
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		value, rest, err := parseTomlString(s)
+		if err != nil {
+			return "", err
+		}
+		if !isTomlEnd(rest) {
+			return "", fmt.Errorf("unexpected %q after the value", strings.TrimSpace(rest))
+		}
+		return value, nil
+	}
+
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if s == "true" || s == "false" {
+		return s, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(s, "_", "", -1), 64); err == nil {
+		return strings.Replace(s, "_", "", -1), nil
+	}
+	if len(s) == 0 {
+		return "", fmt.Errorf("missing value")
+	}
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		return "", fmt.Errorf("arrays and inline tables are not supported")
+	}
+	return "", fmt.Errorf("invalid value %q, strings are written in quotes", s)
+}
+
+// parseTomlString parses the basic ("...") or literal ('...') string at the
+// start of s and returns it and the rest of s.
+func parseTomlString(s string) (string, string, error) {
+
+	if s[0] == '\'' {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("missing closing quote")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			// the escapes of TOML are a subset of the ones of Go
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return value, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("missing closing quote")
+}
+
+func isTomlBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// isTomlEnd reports whether s is empty or a comment.
+func isTomlEnd(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) == 0 || s[0] == '#'
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseToml(t *testing.T) {
+
+	text := `# podcasts
+concurrency = 8   # feeds at a time
+timeout = "30s" # a comment
+user_agent = 'podcasts # not a comment'
+big = 1_000
+ratio = 0.5
+on = true
+escaped = "a\"b\tc"
+
+[feed."https://example.com/feed.xml"]
+include = "(?i)interview"
+max = 3
+
+[ feed . 'https://example.com/b.xml' ]  # spaces around the parts
+dir = "B"
+
+[plain]
+`
+
+	tables, err := parseToml(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, table := range tables {
+		var keys []string
+		for key, v := range table.Values {
+			keys = append(keys, fmt.Sprintf("%s=%q@%d", key, v.Text, v.Line))
+		}
+		sort.Strings(keys)
+		got = append(got, fmt.Sprintf("%q@%d %s", table.Name, table.Line, strings.Join(keys, " ")))
+	}
+
+	want := []string{
+		`[]@0 big="1000"@5 concurrency="8"@2 escaped="a\"b\tc"@8 on="true"@7 ratio="0.5"@6 timeout="30s"@3 user_agent="podcasts # not a comment"@4`,
+		`["feed" "https://example.com/feed.xml"]@10 include="(?i)interview"@11 max="3"@12`,
+		`["feed" "https://example.com/b.xml"]@14 dir="B"@15`,
+		`["plain"]@17 `,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parsed\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseTomlErrors(t *testing.T) {
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"a = 1\na = 2", "2: a set twice"},
+		{"[t]\n[t]", "2: table [t] defined twice"},
+		{`[feed."x"]` + "\n" + `[feed.'x']`, "2: table [feed.x] defined twice"},
+		{"[[t]]", "1: arrays of tables are not supported"},
+		{"a.b = 1", "1: dotted keys are not supported"},
+		{"a = [1, 2]", "1: a: arrays and inline tables are not supported"},
+		{"a = {b = 1}", "1: a: arrays and inline tables are not supported"},
+		{"a = \"\"\"x\"\"\"", `1: a: unexpected "\"x\"\"\"" after the value`},
+		{"a = word", `1: a: invalid value "word", strings are written in quotes`},
+		{"a =", "1: a: missing value"},
+		{"a = \"open", "1: a: missing closing quote"},
+		{"a = 'open", "1: a: missing closing quote"},
+		{`a = "\q"`, `1: a: invalid string "\q"`},
+		{"a", `1: missing = after "a"`},
+		{"= 1", "1: missing key"},
+		{"[t", "1: invalid table header"},
+		{"[t] x", "1: invalid table header"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseToml(tt.text)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("parseToml(%q) = %v, want %s", tt.text, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lastRun is the -since value that starts the window at the last time each
+// feed was fetched.
+const lastRun = "last-run"
+
+// Window selects the episodes of a feed by date.
+type Window struct {
+	// Since and Until bound the publication date, zero for no bound.
+	Since time.Time
+	Until time.Time
+	// SinceLastRun starts the window of each feed at its last successful
+	// fetch instead of Since, Since is used for the feeds never fetched.
+	SinceLastRun bool
+	// Latest keeps only the newest episodes of each feed in the window
+	// when not zero.
+	Latest int
+}
+
+// Start is the beginning of the window for the feed.
+func (w Window) Start(feed string, state *StateDB) time.Time {
+
+	if w.SinceLastRun {
+		if t := state.LastFetch(feed); !t.IsZero() {
+			return t
+		}
+	}
+	return w.Since
+}
+
+// Contains reports whether t is in the window that starts at start.
+func (w Window) Contains(t time.Time, start time.Time) bool {
+
+	if !start.IsZero() && t.Before(start) {
+		return false
+	}
+	if !w.Until.IsZero() && t.After(w.Until) {
+		return false
+	}
+	return true
+}
+
+// ParseTimeBound parses a -since or -until value: a date such as
+// "2026-10-01" or "2026-10-01 18:30" in local time, an RFC 3339 time, or a
+// duration before now such as "36h", "3d" or "2w". A date alone is the
+// start of the day, or its end when endOfDay is set, so -until includes it.
+func ParseTimeBound(s string, now time.Time, endOfDay bool) (time.Time, error) {
+
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+
+	if d, err := parseAge(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+
+	layouts := []string{
+		"2006-01-02 15:04",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, use a date like 2026-10-01 or a duration like 36h or 3d", s)
+}
+
+// parseAge parses a time.Duration or a number of days ("3d") or weeks
+// ("2w").
+func parseAge(s string) (time.Duration, error) {
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) > 1 {
+		if u, ok := unit[s[len(s)-1]]; ok {
+			n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+			if err == nil && n >= 0 {
+				return time.Duration(n * float64(u)), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid duration %q", s)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	day := func(d int, h int, m int) time.Time {
+		return time.Date(2026, 10, d, h, m, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		in       string
+		endOfDay bool
+		want     time.Time
+	}{
+		{"", false, time.Time{}},
+		{"2026-10-15", false, day(15, 0, 0)},
+		// -until includes the whole day
+		{"2026-10-15", true, day(16, 0, 0).Add(-time.Nanosecond)},
+		{"2026-10-15 18:30", true, day(15, 18, 30)},
+		{"2026-10-15T18:30", false, day(15, 18, 30)},
+		{"36h", true, day(17, 0, 0)},
+		{"3d", false, day(15, 12, 0)},
+		{"1w", false, day(11, 12, 0)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.in, now, tt.endOfDay)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q, %v) = %v, %v, want %v", tt.in, tt.endOfDay, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseTimeBound("yesterday", now, false); err == nil {
+		t.Error("ParseTimeBound(\"yesterday\") succeeded")
+	}
+
+	until, _ := ParseTimeBound("2026-10-15", now, true)
+	w := Window{Until: until}
+	if !w.Contains(day(15, 23, 59), time.Time{}) || w.Contains(day(16, 0, 0), time.Time{}) {
+		t.Errorf("-until 2026-10-15 is not the whole day: %v", until)
+	}
+}